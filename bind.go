@@ -0,0 +1,308 @@
+package valse
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// Struct tags understood by Bind.
+const (
+	tagParam  = "param"
+	tagQuery  = "query"
+	tagForm   = "form"
+	tagHeader = "header"
+)
+
+// FieldError describes why a single field failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Bind fills dst, which must be a pointer to a struct, from the request and
+// validates it with the server's validator.
+//
+// The body is decoded according to the Content-Type header: JSON and XML are
+// unmarshalled into dst, while url-encoded and multipart forms fill fields
+// tagged with `form:"name"`. Fields tagged with `param:"name"`,
+// `query:"name"` and `header:"Name"` are then filled from the path
+// parameters, the query string and the request headers, in that order.
+//
+// If decoding fails a 400 Entity is returned, an unknown Content-Type gives
+// ErrUnsupportedMediaType and failed validation gives a 422 Entity whose
+// Errors lists the offending fields.
+func (c *Context) Bind(dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.New("bind: destination must be a pointer to a struct")
+	}
+
+	if err := c.bindBody(dst); err != nil {
+		return err
+	}
+
+	err := bindValues(v.Elem(), tagParam, func(name string) ([]string, bool) {
+		if s, ok := c.UserValue(name).(string); ok {
+			return []string{s}, true
+		}
+		return nil, false
+	})
+	if err != nil {
+		return NewHTTPMessage(StatusBadRequest, err.Error())
+	}
+
+	args := c.URI().QueryArgs()
+	err = bindValues(v.Elem(), tagQuery, func(name string) ([]string, bool) {
+		return peekMulti(args.PeekMulti(name))
+	})
+	if err != nil {
+		return NewHTTPMessage(StatusBadRequest, err.Error())
+	}
+
+	err = bindValues(v.Elem(), tagHeader, func(name string) ([]string, bool) {
+		if h := c.Request.Header.Peek(name); h != nil {
+			return []string{string(h)}, true
+		}
+		return nil, false
+	})
+	if err != nil {
+		return NewHTTPMessage(StatusBadRequest, err.Error())
+	}
+
+	return c.Validate(dst)
+}
+
+// Validate runs the server's validator against v. Validation failures are
+// returned as a 422 Entity listing the field errors.
+func (c *Context) Validate(v interface{}) error {
+	if c.s == nil || c.s.v == nil {
+		return ErrValidatorNotRegistered
+	}
+
+	err := c.s.v.Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	verrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	e := BuildEntity(StatusUnprocessableEntity, StatusText(StatusUnprocessableEntity))
+	for _, fe := range verrs {
+		e.Errors = append(e.Errors, FieldError{
+			Field:   fe.Field(),
+			Tag:     fe.Tag(),
+			Param:   fe.Param(),
+			Message: fieldErrorMessage(fe),
+		})
+	}
+
+	return e
+}
+
+func (c *Context) bindBody(dst interface{}) error {
+	body := c.PostBody()
+	ctype := string(c.Request.Header.ContentType())
+	if i := strings.IndexByte(ctype, ';'); i != -1 {
+		ctype = ctype[:i]
+	}
+	ctype = strings.TrimSpace(ctype)
+
+	switch {
+	case ctype == MIMEApplicationForm:
+		args := c.PostArgs()
+		if err := bindValues(reflect.ValueOf(dst).Elem(), tagForm, func(name string) ([]string, bool) {
+			return peekMulti(args.PeekMulti(name))
+		}); err != nil {
+			return NewHTTPMessage(StatusBadRequest, err.Error())
+		}
+		return nil
+	case ctype == MIMEMultipartForm:
+		form, err := c.MultipartForm()
+		if err != nil {
+			return NewHTTPMessage(StatusBadRequest, err.Error())
+		}
+		if err := bindValues(reflect.ValueOf(dst).Elem(), tagForm, func(name string) ([]string, bool) {
+			v, ok := form.Value[name]
+			return v, ok
+		}); err != nil {
+			return NewHTTPMessage(StatusBadRequest, err.Error())
+		}
+		return nil
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	switch ctype {
+	case MIMEApplicationJSON:
+		if err := json.Unmarshal(body, dst); err != nil {
+			return NewHTTPMessage(StatusBadRequest, err.Error())
+		}
+	case MIMEApplicationXML, "text/xml":
+		if err := xml.Unmarshal(body, dst); err != nil {
+			return NewHTTPMessage(StatusBadRequest, err.Error())
+		}
+	default:
+		return ErrUnsupportedMediaType
+	}
+
+	return nil
+}
+
+func peekMulti(values [][]byte) ([]string, bool) {
+	if len(values) == 0 {
+		return nil, false
+	}
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out, true
+}
+
+// bindValues sets every field of v tagged with tag to the values returned by
+// lookup. Untagged embedded structs are descended into.
+func bindValues(v reflect.Value, tag string, lookup func(name string) ([]string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name := field.Tag.Get(tag)
+		if name == "" || name == "-" {
+			if field.Anonymous && fv.Kind() == reflect.Struct {
+				if err := bindValues(fv, tag, lookup); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		values, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		if err := setField(fv, values); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func setField(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(s.Index(i), value); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+
+	return setValue(v, values[0])
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Slice:
+		v.SetBytes([]byte(value))
+	case reflect.Bool:
+		if value == "" {
+			value = "false"
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == "" {
+			value = "0"
+		}
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value == "" {
+			value = "0"
+		}
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if value == "" {
+			value = "0"
+		}
+		n, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported kind %s", v.Kind())
+	}
+	return nil
+}
+
+func fieldErrorMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", fe.Field())
+	case "min", "gte":
+		return fmt.Sprintf("%s must be at least %s", fe.Field(), fe.Param())
+	case "max", "lte":
+		return fmt.Sprintf("%s must be at most %s", fe.Field(), fe.Param())
+	case "len":
+		return fmt.Sprintf("%s must have length %s", fe.Field(), fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s]", fe.Field(), fe.Param())
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("%s failed on %s=%s", fe.Field(), fe.Tag(), fe.Param())
+	}
+	return fmt.Sprintf("%s failed on %s", fe.Field(), fe.Tag())
+}
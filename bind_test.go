@@ -0,0 +1,84 @@
+package valse
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+type bindUser struct {
+	ID     int      `param:"id" json:"-"`
+	Name   string   `json:"name" validate:"required"`
+	Age    int      `json:"age" validate:"gte=0,lte=130"`
+	Tags   []string `query:"tag" json:"-"`
+	Client string   `header:"X-Client" json:"-"`
+}
+
+func newBindContext(method, uri, ctype, body string) *Context {
+	s := New()
+	rc := &fasthttp.RequestCtx{}
+	rc.Request.Header.SetMethod(method)
+	rc.Request.SetRequestURI(uri)
+	rc.Request.Header.SetContentType(ctype)
+	rc.Request.Header.Set("X-Client", "test")
+	rc.Request.SetBodyString(body)
+	return &Context{RequestCtx: rc, s: s}
+}
+
+func TestBindJSON(t *testing.T) {
+	ctx := newBindContext("POST", "/users/7?tag=a&tag=b", MIMEApplicationJSON, `{"name":"rob","age":30}`)
+	ctx.SetUserValue("id", "7")
+
+	var u bindUser
+	if err := ctx.Bind(&u); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 7 || u.Name != "rob" || u.Age != 30 || u.Client != "test" {
+		t.Fatalf("unexpected result %+v", u)
+	}
+	if len(u.Tags) != 2 || u.Tags[0] != "a" || u.Tags[1] != "b" {
+		t.Fatalf("unexpected tags %v", u.Tags)
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	type form struct {
+		Name string `form:"name" validate:"required"`
+		Age  int    `form:"age"`
+	}
+	ctx := newBindContext("POST", "/", MIMEApplicationForm, "name=rob&age=30")
+
+	var f form
+	if err := ctx.Bind(&f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Name != "rob" || f.Age != 30 {
+		t.Fatalf("unexpected result %+v", f)
+	}
+}
+
+func TestBindValidation(t *testing.T) {
+	ctx := newBindContext("POST", "/", MIMEApplicationJSON, `{"age":200}`)
+
+	var u bindUser
+	err := ctx.Bind(&u)
+	e, ok := err.(*Entity)
+	if !ok {
+		t.Fatalf("expected *Entity, got %v", err)
+	}
+	if e.Code != StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", e.Code)
+	}
+	if len(e.Errors) != 2 || e.Errors[0].Field != "name" || e.Errors[1].Field != "age" {
+		t.Fatalf("unexpected field errors %+v", e.Errors)
+	}
+}
+
+func TestBindUnsupportedMediaType(t *testing.T) {
+	ctx := newBindContext("POST", "/", "application/yaml", "name: rob")
+
+	var u bindUser
+	if err := ctx.Bind(&u); err != ErrUnsupportedMediaType {
+		t.Fatalf("expected ErrUnsupportedMediaType, got %v", err)
+	}
+}
@@ -17,20 +17,70 @@ var (
 type Context struct {
 	noCopy
 	*fasthttp.RequestCtx
-	log Logger
+	log FieldLogger
 	s   *Server
+
+	links     *Links
+	paginator *Paginator
+	cursor    *CursorPaginator
 }
 
 func (c *Context) reset() *Context {
 	c.RequestCtx = nil
+	c.log = nil
+	c.links = nil
+	c.paginator = nil
+	c.cursor = nil
 	return c
 }
 
-// Log log function
-func (c *Context) Log() Logger {
+// Log returns the logger of the request. It is derived from Config.Logger
+// with the request id, method, path and remote address attached.
+func (c *Context) Log() FieldLogger {
+	if c.log == nil {
+		var l FieldLogger
+		if c.s != nil && c.s.logger != nil {
+			l = c.s.logger
+		} else {
+			l = NewFieldLogger(nil)
+		}
+
+		fields := Fields{
+			"method": string(c.Method()),
+			"path":   string(c.Path()),
+			"remote": c.RemoteAddr().String(),
+		}
+		if id := c.RequestID(); id != "" {
+			fields["request_id"] = id
+		}
+		c.log = l.WithFields(fields)
+	}
 	return c.log
 }
 
+const requestIDKey = "valse.request_id"
+
+// RequestID returns the id set with SetRequestID, usually by the requestid
+// middleware, or an empty string. The id sent by the client is not used
+// unless the middleware validated it.
+func (c *Context) RequestID() string {
+	id, _ := c.UserValue(requestIDKey).(string)
+	return id
+}
+
+// SetRequestID sets the id of the request. It is kept for the whole request,
+// including the contexts of the route handlers.
+func (c *Context) SetRequestID(id string) {
+	c.SetUserValue(requestIDKey, id)
+	c.log = nil
+}
+
+// URL builds the path of the named route. See Server.URL. Errors are logged
+// to the request logger.
+func (c *Context) URL(name string, params ...interface{}) string {
+	return c.s.url(c.Log(), name, params)
+}
+
 // Status sets the response status code.
 func (c *Context) Status(status int) *Context {
 	c.SetStatusCode(status)
@@ -0,0 +1,177 @@
+package valse
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cursor directions.
+const (
+	CursorNext = "next"
+	CursorPrev = "prev"
+)
+
+var (
+	errInvalidCursor = errors.New("invalid cursor")
+	errExpiredCursor = errors.New("expired cursor")
+)
+
+type cursorToken struct {
+	Direction string      `json:"d"`
+	Position  interface{} `json:"p"`
+	// IssuedAt is the Unix time the token was signed at.
+	IssuedAt int64 `json:"t"`
+}
+
+// CursorPaginator pages through a result set with opaque, signed cursor
+// tokens instead of offsets. The position stored in a token is whatever the
+// handler needs to continue the query, usually the sort key of the last row
+// it returned.
+type CursorPaginator struct {
+	c         *Context
+	secret    []byte
+	maxAge    time.Duration
+	param     string
+	direction string
+	limit     int
+	links     []*Link
+}
+
+// Cursor instantiates a cursor paginator for the current request. If the
+// request carries a cursor token its position is unmarshalled into position,
+// which must be a pointer, and true is returned. A tampered, malformed or
+// expired token gives a 400 Entity.
+func (c *Context) Cursor(position interface{}) (*CursorPaginator, bool, error) {
+	cfg := c.paginationConfig()
+
+	limit := cfg.PerPage
+	if n, err := strconv.Atoi(string(c.URI().QueryArgs().Peek(cfg.PerPageParam))); err == nil && n > 0 {
+		limit = n
+	}
+	if limit > cfg.MaxPerPage {
+		limit = cfg.MaxPerPage
+	}
+
+	p := &CursorPaginator{
+		c:         c,
+		secret:    cfg.CursorSecret,
+		maxAge:    cfg.CursorMaxAge,
+		param:     cfg.CursorParam,
+		direction: CursorNext,
+		limit:     limit,
+	}
+
+	token := string(c.URI().QueryArgs().Peek(cfg.CursorParam))
+	if token == "" {
+		return p, false, nil
+	}
+
+	t := cursorToken{Position: position}
+	if err := p.decode(token, &t); err != nil {
+		return nil, false, NewHTTPMessage(StatusBadRequest, err.Error())
+	}
+	if t.Direction == CursorPrev {
+		p.direction = CursorPrev
+	}
+
+	return p, true, nil
+}
+
+// Limit returns the number of items to return.
+func (p *CursorPaginator) Limit() int {
+	return p.limit
+}
+
+// Direction returns CursorPrev if the request cursor points backwards, and
+// CursorNext otherwise.
+func (p *CursorPaginator) Direction() string {
+	return p.direction
+}
+
+// SetNext sets the position the next page starts after and adds the next
+// link to the Link header.
+func (p *CursorPaginator) SetNext(position interface{}) error {
+	return p.set(CursorNext, position)
+}
+
+// SetPrev sets the position the previous page ends before and adds the prev
+// link to the Link header.
+func (p *CursorPaginator) SetPrev(position interface{}) error {
+	return p.set(CursorPrev, position)
+}
+
+// Links returns the links set with SetNext and SetPrev.
+func (p *CursorPaginator) Links() []*Link {
+	return p.links
+}
+
+func (p *CursorPaginator) set(direction string, position interface{}) error {
+	token, err := p.encode(cursorToken{direction, position, time.Now().Unix()})
+	if err != nil {
+		return err
+	}
+
+	link, err := url.ParseRequestURI(string(p.c.RequestURI()))
+	if err != nil {
+		return err
+	}
+	values := link.Query()
+	values.Set(p.param, token)
+	link.RawQuery = values.Encode()
+
+	for i, l := range p.links {
+		if l.Rel == direction {
+			p.links = append(p.links[:i], p.links[i+1:]...)
+			break
+		}
+	}
+	p.links = append(p.links, &Link{Rel: direction, Href: link.String(), Method: GET})
+
+	p.c.Response.Header.Set(HeaderLink, linkHeader(p.links))
+	p.c.cursor = p
+	return nil
+}
+
+// encode returns base64url(json(t)) "." base64url(hmac-sha256).
+func (p *CursorPaginator) encode(t cursorToken) (string, error) {
+	bs, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(bs)
+	return payload + "." + p.sign(payload), nil
+}
+
+func (p *CursorPaginator) decode(token string, t *cursorToken) error {
+	i := strings.LastIndexByte(token, '.')
+	if i == -1 {
+		return errInvalidCursor
+	}
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(p.sign(payload))) {
+		return errInvalidCursor
+	}
+	bs, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return errInvalidCursor
+	}
+	if err := json.Unmarshal(bs, t); err != nil {
+		return errInvalidCursor
+	}
+	if time.Since(time.Unix(t.IssuedAt, 0)) > p.maxAge {
+		return errExpiredCursor
+	}
+	return nil
+}
+
+func (p *CursorPaginator) sign(payload string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
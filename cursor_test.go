@@ -0,0 +1,79 @@
+package valse
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func newCursorContext(uri string) *Context {
+	rc := &fasthttp.RequestCtx{}
+	rc.Request.SetRequestURI(uri)
+	return &Context{RequestCtx: rc}
+}
+
+func TestCursor(t *testing.T) {
+	// A context without a server uses the default pagination config.
+	c := newCursorContext("/items")
+	p, ok, err := c.Cursor(nil)
+	if err != nil || ok {
+		t.Fatalf("Cursor() = %v, %v, expected no cursor", ok, err)
+	}
+	if p.Limit() != DefaultPerPage {
+		t.Errorf("expected limit %d, got %d", DefaultPerPage, p.Limit())
+	}
+	if err := p.SetNext(42); err != nil {
+		t.Fatal(err)
+	}
+	valid, err := p.encode(cursorToken{CursorNext, 42, time.Now().Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := p.encode(cursorToken{CursorPrev, 42, time.Now().Add(-DefaultCursorMaxAge - time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		position int
+		err      bool
+	}{
+		{name: "valid", token: valid, position: 42},
+		{name: "tampered", token: "x" + valid, err: true},
+		{name: "unsigned", token: valid[:len(valid)-2], err: true},
+		{name: "malformed", token: "abc", err: true},
+		{name: "expired", token: expired, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCursorContext("/items?cursor=" + url.QueryEscape(tt.token))
+			var position int
+			_, ok, err := c.Cursor(&position)
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil || !ok {
+				t.Fatalf("Cursor() = %v, %v", ok, err)
+			}
+			if position != tt.position {
+				t.Errorf("expected position %d, got %d", tt.position, position)
+			}
+		})
+	}
+}
+
+func TestPaginateWithoutServer(t *testing.T) {
+	c := newCursorContext("/items?p=2")
+	p := c.Paginate(25)
+	if p.Page() != 2 || p.PageNums() != 3 {
+		t.Errorf("expected page 2 of 3, got %d of %d", p.Page(), p.PageNums())
+	}
+}
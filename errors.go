@@ -0,0 +1,97 @@
+package valse
+
+import (
+	"errors"
+
+	"github.com/kildevaeld/strong"
+)
+
+// MIMEApplicationProblemJSON is the media type of RFC 7807 problem documents.
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// ErrorHandler handles an error returned by a RequestHandler.
+type ErrorHandler func(*Context, error)
+
+// Problem is an RFC 7807 problem document.
+// See: https://tools.ietf.org/html/rfc7807
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     int          `json:"code,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+
+	// RequestID correlates the problem with the server logs.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// NewProblem converts err to a problem document. *Entity and
+// *strong.HTTPError values, also when wrapped, keep their code and message;
+// every other error becomes a 500 whose detail is err.Error(), or empty if
+// hideInternal is set.
+func NewProblem(err error, hideInternal bool) *Problem {
+	p := &Problem{
+		Type:   "about:blank",
+		Status: StatusInternalServerError,
+	}
+
+	var entity *Entity
+	var httpErr *strong.HTTPError
+
+	switch {
+	case errors.As(err, &entity):
+		p.Code = entity.EntityCode()
+		if isHTTPStatus(p.Code) {
+			p.Status = p.Code
+		}
+		p.Detail = entity.Message()
+		p.Errors = entity.Errors
+	case errors.As(err, &httpErr):
+		if isHTTPStatus(httpErr.Code) {
+			p.Status = httpErr.Code
+		}
+		p.Code = httpErr.Code
+		p.Detail = httpErr.Error()
+	default:
+		if !hideInternal {
+			p.Detail = err.Error()
+		}
+	}
+
+	p.Title = StatusText(p.Status)
+	if p.Detail == p.Title {
+		p.Detail = ""
+	}
+
+	return p
+}
+
+// DefaultErrorHandler writes err as an RFC 7807 JSON problem document.
+// Internal errors are logged and, in production mode, their text is not sent
+// to the client.
+func DefaultErrorHandler(ctx *Context, err error) {
+	hideInternal := ctx.s != nil && ctx.s.production
+	p := NewProblem(err, hideInternal)
+	p.Instance = string(ctx.Path())
+	p.RequestID = ctx.RequestID()
+
+	if p.Status >= StatusInternalServerError {
+		ctx.Log().WithError(err).Error("request failed")
+	}
+
+	bs, merr := json.Marshal(p)
+	if merr != nil {
+		ctx.Error(StatusText(StatusInternalServerError), StatusInternalServerError)
+		return
+	}
+
+	ctx.SetStatusCode(p.Status)
+	ctx.Response.Header.Set(HeaderContentType, MIMEApplicationProblemJSON)
+	ctx.Response.SetBody(bs)
+}
+
+func isHTTPStatus(code int) bool {
+	return code >= 100 && code < 600
+}
@@ -0,0 +1,161 @@
+package valse
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/kildevaeld/strong"
+	"github.com/valyala/fasthttp"
+)
+
+func TestNewProblem(t *testing.T) {
+	fields := []FieldError{{Field: "name", Message: "is required"}}
+
+	tests := []struct {
+		name         string
+		err          error
+		hideInternal bool
+		want         Problem
+	}{
+		{
+			name: "entity",
+			err:  BuildEntity(StatusNotFound, "no such user"),
+			want: Problem{Status: StatusNotFound, Title: "Not Found", Detail: "no such user", Code: StatusNotFound},
+		},
+		{
+			name: "entity with status text",
+			err:  NewHTTPMessage(StatusConflict),
+			want: Problem{Status: StatusConflict, Title: "Conflict", Code: StatusConflict},
+		},
+		{
+			name: "entity with application code",
+			err:  BuildEntity(10001, "quota exceeded"),
+			want: Problem{Status: StatusInternalServerError, Title: "Internal Server Error", Detail: "quota exceeded", Code: 10001},
+		},
+		{
+			name: "entity with field errors",
+			err:  &Entity{Code: StatusUnprocessableEntity, Msg: "invalid", Errors: fields},
+			want: Problem{Status: StatusUnprocessableEntity, Title: "Unprocessable Entity", Detail: "invalid", Code: StatusUnprocessableEntity, Errors: fields},
+		},
+		{
+			name:         "entity in production",
+			err:          BuildEntity(StatusForbidden, "not yours"),
+			hideInternal: true,
+			want:         Problem{Status: StatusForbidden, Title: "Forbidden", Detail: "not yours", Code: StatusForbidden},
+		},
+		{
+			name: "http error",
+			err:  strong.NewHTTPError(StatusBadRequest, "bad id"),
+			want: Problem{Status: StatusBadRequest, Title: "Bad Request", Detail: "bad id", Code: StatusBadRequest},
+		},
+		{
+			name: "http error with status text",
+			err:  strong.ErrUnauthorized,
+			want: Problem{Status: StatusUnauthorized, Title: "Unauthorized", Code: StatusUnauthorized},
+		},
+		{
+			name: "wrapped entity",
+			err:  fmt.Errorf("load user: %w", BuildEntity(StatusNotFound, "no such user")),
+			want: Problem{Status: StatusNotFound, Title: "Not Found", Detail: "no such user", Code: StatusNotFound},
+		},
+		{
+			name: "wrapped http error",
+			err:  fmt.Errorf("auth: %w", strong.NewHTTPError(StatusForbidden, "denied")),
+			want: Problem{Status: StatusForbidden, Title: "Forbidden", Detail: "denied", Code: StatusForbidden},
+		},
+		{
+			name: "internal",
+			err:  errors.New("db: connection refused"),
+			want: Problem{Status: StatusInternalServerError, Title: "Internal Server Error", Detail: "db: connection refused"},
+		},
+		{
+			name:         "internal in production",
+			err:          errors.New("db: connection refused"),
+			hideInternal: true,
+			want:         Problem{Status: StatusInternalServerError, Title: "Internal Server Error"},
+		},
+		{
+			name:         "wrapped internal in production",
+			err:          fmt.Errorf("load user: %w", errors.New("db: connection refused")),
+			hideInternal: true,
+			want:         Problem{Status: StatusInternalServerError, Title: "Internal Server Error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Type = "about:blank"
+			if got := NewProblem(tt.err, tt.hideInternal); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, *got)
+			}
+		})
+	}
+}
+
+func TestDefaultErrorHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		production bool
+		err        error
+		status     int
+		detail     string
+	}{
+		{name: "entity", err: BuildEntity(StatusNotFound, "no such user"), status: StatusNotFound, detail: "no such user"},
+		{name: "http error", err: strong.NewHTTPError(StatusBadRequest, "bad id"), status: StatusBadRequest, detail: "bad id"},
+		{name: "internal", err: errors.New("db down"), status: StatusInternalServerError, detail: "db down"},
+		{name: "internal in production", production: true, err: errors.New("db down"), status: StatusInternalServerError},
+		{name: "entity in production", production: true, err: BuildEntity(StatusNotFound, "no such user"), status: StatusNotFound, detail: "no such user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWithConfig(Config{Production: tt.production, Logger: nopLogger{}})
+			s.Use(func(c *Context, next RequestHandler) error {
+				c.SetRequestID("abc")
+				return next(c)
+			})
+			s.Get("/users/:id", func(c *Context) error {
+				return tt.err
+			})
+
+			rc := &fasthttp.RequestCtx{}
+			rc.Request.SetRequestURI("/users/7?x=1")
+			s.GetHandler()(rc)
+
+			if got := rc.Response.StatusCode(); got != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, got)
+			}
+			if got := string(rc.Response.Header.ContentType()); got != MIMEApplicationProblemJSON {
+				t.Errorf("expected Content-Type %s, got %s", MIMEApplicationProblemJSON, got)
+			}
+			var p Problem
+			if err := json.Unmarshal(rc.Response.Body(), &p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Status != tt.status || p.Detail != tt.detail {
+				t.Errorf("expected status %d and detail %q, got %+v", tt.status, tt.detail, p)
+			}
+			if p.Instance != "/users/7" {
+				t.Errorf("expected instance /users/7, got %q", p.Instance)
+			}
+			if p.RequestID != "abc" {
+				t.Errorf("expected request id abc, got %q", p.RequestID)
+			}
+		})
+	}
+}
+
+// nopLogger discards what it is given.
+type nopLogger struct{}
+
+func (nopLogger) Print(...interface{})          {}
+func (nopLogger) Printf(string, ...interface{}) {}
+func (nopLogger) Println(...interface{})        {}
+func (nopLogger) Fatal(...interface{})          {}
+func (nopLogger) Fatalf(string, ...interface{}) {}
+func (nopLogger) Fatalln(...interface{})        {}
+func (nopLogger) Panic(...interface{})          {}
+func (nopLogger) Panicf(string, ...interface{}) {}
+func (nopLogger) Panicln(...interface{})        {}
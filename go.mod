@@ -0,0 +1,46 @@
+module github.com/xwinie/valse
+
+go 1.24.0
+
+require (
+	github.com/aarzilli/golua v0.0.0-20250217091409-248753f411c4
+	github.com/buaazp/fasthttprouter v0.1.1
+	github.com/casbin/casbin v1.9.1
+	github.com/dgrijalva/jwt-go v3.2.0+incompatible
+	github.com/golang/protobuf v1.5.4
+	github.com/google/uuid v1.6.0
+	github.com/hashicorp/go-multierror v1.1.1
+	github.com/json-iterator/go v1.1.12
+	github.com/kildevaeld/strong v0.0.0-00010101000000-000000000000
+	github.com/oklog/ulid v1.3.1
+	github.com/sirupsen/logrus v1.9.3
+	github.com/stevedonovan/luar v0.0.0-00010101000000-000000000000
+	github.com/valyala/fasthttp v1.65.0
+	github.com/vmihailenco/msgpack v4.0.4+incompatible
+	github.com/yuin/gopher-lua v1.1.1
+	gopkg.in/go-playground/validator.v9 v9.31.0
+	layeh.com/gopher-luar v1.0.10
+)
+
+require (
+	github.com/Knetic/govaluate v3.0.1-0.20171022003610-9aa49832a739+incompatible // indirect
+	github.com/andybalholm/brotli v1.2.0 // indirect
+	github.com/go-playground/locales v0.14.1 // indirect
+	github.com/go-playground/universal-translator v0.18.1 // indirect
+	github.com/hashicorp/errwrap v1.1.0 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/leodido/go-urn v1.2.4 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
+	github.com/modern-go/reflect2 v1.0.2 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	golang.org/x/net v0.43.0 // indirect
+	golang.org/x/sys v0.37.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
+
+// No version of strong or luar can be fetched from the module proxy. These
+// replacements declare the parts of their API valse uses.
+replace (
+	github.com/kildevaeld/strong => ./third_party/strong
+	github.com/stevedonovan/luar => ./third_party/luar
+)
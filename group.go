@@ -71,14 +71,32 @@ func (g *Group) Route(method, path string, handlers ...interface{}) *Group {
 	}
 
 	g.r = append(g.r, Route{
-		Method:  method,
-		Path:    path,
-		Handler: handler,
+		Method:      method,
+		Path:        path,
+		Handler:     handler,
+		Middlewares: len(handlers) - 1,
 	})
 
 	return g
 }
 
+// Name names the most recently registered route of the group. The name is
+// kept when the group is mounted.
+func (g *Group) Name(name string) *Group {
+	if len(g.r) == 0 {
+		panic("no route to name")
+	}
+	g.r[len(g.r)-1].Name = name
+	return g
+}
+
+// Routes returns the routes of the group in registration order.
+func (g *Group) Routes() []Route {
+	out := make([]Route, len(g.r))
+	copy(out, g.r)
+	return out
+}
+
 func (s *Group) Mount(path string, group *Group) *Group {
 	for _, route := range group.r {
 		p := route.Path
@@ -86,7 +104,18 @@ func (s *Group) Mount(path string, group *Group) *Group {
 			p = filepath.Join(path, route.Path)
 		}
 
-		s.Route(route.Method, p, cpy(group.m, route.Handler)...)
+		handler, err := compose(cpy(group.m, route.Handler))
+		if err != nil {
+			panic(err)
+		}
+
+		s.r = append(s.r, Route{
+			Method:      route.Method,
+			Path:        p,
+			Name:        route.Name,
+			Handler:     handler,
+			Middlewares: len(group.m) + route.Middlewares,
+		})
 	}
 	return s
 }
@@ -1,7 +1,32 @@
 package valse
 
 import (
+	"bytes"
+	"fmt"
+	"net/url"
 	"path"
+	"reflect"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// LinkStyle selects how Context.Resource embeds links.
+type LinkStyle int
+
+const (
+	// LinkStyleHAL embeds links as a "_links" object keyed by rel, see
+	// https://tools.ietf.org/html/draft-kelly-json-hal
+	LinkStyleHAL LinkStyle = iota
+	// LinkStyleJSONAPI wraps the resource in "data" next to a "links"
+	// object, see http://jsonapi.org/format/#document-links
+	LinkStyleJSONAPI
+)
+
+// Media types of the documents written by Context.Resource.
+const (
+	MIMEApplicationHALJSON = "application/hal+json"
+	MIMEApplicationJSONAPI = "application/vnd.api+json"
 )
 
 type Link struct {
@@ -40,6 +65,11 @@ func (l *Links) Add(link *Link) *Links {
 	return l
 }
 
+// List returns the links in the order they were added.
+func (l *Links) List() []*Link {
+	return l.links
+}
+
 func (l *Links) MarshalJSON() ([]byte, error) {
 	return json.Marshal(l.links)
 }
@@ -67,3 +97,208 @@ func LinkTo(href string, rel string, method string, title string) *Link {
 	}
 	return &Link{Rel: "self", Href: "/"}
 }
+
+// buildURL replaces the named (:name) and catch-all (*name) parameters of a
+// route path with params, in order. It fails unless there is exactly one
+// param per parameter.
+func buildURL(routePath string, params []interface{}) (string, error) {
+	segments := strings.Split(routePath, "/")
+	n := 0
+	for i, segment := range segments {
+		if len(segment) == 0 || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+		if n >= len(params) {
+			return "", fmt.Errorf("missing value for parameter %s of %s", segment, routePath)
+		}
+		value := fmt.Sprint(params[n])
+		if segment[0] == ':' {
+			value = url.PathEscape(value)
+		} else {
+			value = strings.TrimPrefix(value, "/")
+		}
+		segments[i] = value
+		n++
+	}
+	if n < len(params) {
+		return "", fmt.Errorf("%s takes %d parameters, got %d", routePath, n, len(params))
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// Links returns the links of the current request. Links added to it are
+// embedded by Resource.
+func (c *Context) Links() *Links {
+	if c.links == nil {
+		c.links = &Links{}
+	}
+	return c.links
+}
+
+// SetPaginator sets the paginator Resource uses to add first, prev, next and
+// last links to collections.
+func (c *Context) SetPaginator(p *Paginator) *Context {
+	c.paginator = p
+	return c
+}
+
+// Resource writes v with the given status and its links embedded in the
+// server's LinkStyle. The links are the request's Links, then links, then,
+// when v is a slice or an array and Paginate or Cursor was used, the
+// pagination links. A self link to the request URI is added if none is given.
+func (c *Context) Resource(status int, v interface{}, links ...*Link) error {
+	var all []*Link
+	if c.links != nil {
+		all = append(all, c.links.links...)
+	}
+	for _, link := range links {
+		if link != nil {
+			all = append(all, link)
+		}
+	}
+
+	collection := isCollection(v)
+	if collection && c.paginator != nil {
+		all = append(all, c.pageLinks(c.paginator)...)
+	}
+	if collection && c.cursor != nil {
+		all = append(all, c.cursor.Links()...)
+	}
+
+	hasSelf := false
+	for _, link := range all {
+		if link.Rel == "self" {
+			hasSelf = true
+			break
+		}
+	}
+	if !hasSelf {
+		all = append([]*Link{{Rel: "self", Href: string(c.RequestURI()), Method: string(c.Method())}}, all...)
+	}
+
+	var doc interface{}
+	contentType := MIMEApplicationHALJSON
+	if c.s != nil && c.s.linkStyle == LinkStyleJSONAPI {
+		contentType = MIMEApplicationJSONAPI
+		doc = jsonAPIDocument(v, all)
+	} else {
+		d, err := halDocument(v, all, collection)
+		if err != nil {
+			return err
+		}
+		doc = d
+	}
+
+	bs, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	c.SetStatusCode(status)
+	c.Response.Header.Set(HeaderContentType, contentType)
+	c.Response.SetBody(bs)
+	return nil
+}
+
+func (c *Context) pageLinks(p *Paginator) []*Link {
+	href := p.PageLink
+	if c.s != nil && c.s.links != nil {
+		uri := (*URI)(c.URI())
+		href = func(page int) string {
+			return c.s.links(c, copyURI(uri), page)
+		}
+	}
+
+	var links []*Link
+	if p.PageNums() == 0 {
+		return links
+	}
+	links = append(links, &Link{Rel: "first", Href: href(1), Method: GET})
+	if p.HasPrev() {
+		links = append(links, &Link{Rel: "prev", Href: href(p.Page() - 1), Method: GET})
+	}
+	if p.HasNext() {
+		links = append(links, &Link{Rel: "next", Href: href(p.Page() + 1), Method: GET})
+	}
+	links = append(links, &Link{Rel: "last", Href: href(p.PageNums()), Method: GET})
+	return links
+}
+
+// copyURI returns a deep copy of u for a LinksFactory, which takes it by
+// value.
+func copyURI(u *URI) (uri URI) {
+	(*fasthttp.URI)(u).CopyTo((*fasthttp.URI)(&uri))
+	return
+}
+
+func isCollection(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+}
+
+// halDocument merges the _links object into v. Collections are put in
+// _embedded.items, as is anything that does not encode to a JSON object.
+func halDocument(v interface{}, links []*Link, collection bool) (map[string]interface{}, error) {
+	rels := map[string]interface{}{}
+	for _, link := range links {
+		l := map[string]string{"href": link.Href}
+		if link.Method != "" {
+			l["method"] = link.Method
+		}
+		if link.Title != "" {
+			l["title"] = link.Title
+		}
+		switch prev := rels[link.Rel].(type) {
+		case nil:
+			rels[link.Rel] = l
+		case []map[string]string:
+			rels[link.Rel] = append(prev, l)
+		case map[string]string:
+			rels[link.Rel] = []map[string]string{prev, l}
+		}
+	}
+
+	doc := map[string]interface{}{}
+	if !collection && v != nil {
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		// Numbers are kept as json.Number, float64 would round integers
+		// above 2^53.
+		dec := json.NewDecoder(bytes.NewReader(bs))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			doc = map[string]interface{}{}
+			collection = true
+		}
+		if doc == nil {
+			doc = map[string]interface{}{}
+		}
+	}
+	if collection {
+		doc["_embedded"] = map[string]interface{}{"items": v}
+	}
+	doc["_links"] = rels
+
+	return doc, nil
+}
+
+func jsonAPIDocument(v interface{}, links []*Link) map[string]interface{} {
+	rels := map[string]string{}
+	for _, link := range links {
+		if _, ok := rels[link.Rel]; !ok {
+			rels[link.Rel] = link.Href
+		}
+	}
+	return map[string]interface{}{
+		"data":  v,
+		"links": rels,
+	}
+}
@@ -0,0 +1,148 @@
+package valse
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestURL(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewWithConfig(Config{Logger: log.New(&buf, "", 0)})
+	s.Get("/users/:id", func(c *Context) error { return nil }).Name("user.show")
+	s.Get("/users/:id/posts/:post", func(c *Context) error { return nil }).Name("post.show")
+	s.Get("/static/*path", func(c *Context) error { return nil }).Name("static")
+
+	tests := []struct {
+		name   string
+		params []interface{}
+		want   string
+		// logged is set if building the URL fails with a logged error.
+		logged bool
+	}{
+		{name: "user.show", params: []interface{}{1}, want: "/users/1"},
+		{name: "user.show", params: []interface{}{"a b"}, want: "/users/a%20b"},
+		{name: "post.show", params: []interface{}{1, 2}, want: "/users/1/posts/2"},
+		{name: "static", params: []interface{}{"/css/site.css"}, want: "/static/css/site.css"},
+		{name: "unknown", want: ""},
+		{name: "user.show", logged: true},
+		{name: "post.show", params: []interface{}{1}, logged: true},
+		{name: "user.show", params: []interface{}{1, 2}, logged: true},
+	}
+
+	for _, tt := range tests {
+		buf.Reset()
+		if got := s.URL(tt.name, tt.params...); got != tt.want {
+			t.Errorf("URL(%q, %v) = %q, want %q", tt.name, tt.params, got, tt.want)
+		}
+		if logged := strings.Contains(buf.String(), "[ERROR] cannot build URL"); logged != tt.logged {
+			t.Errorf("URL(%q, %v): expected logged %v, got %q", tt.name, tt.params, tt.logged, buf.String())
+		}
+		if link := s.Link(tt.name, tt.params...); (link != nil) != (tt.want != "") {
+			t.Errorf("Link(%q, %v) = %v, want href %q", tt.name, tt.params, link, tt.want)
+		}
+	}
+}
+
+func TestContextURL(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewWithConfig(Config{Logger: log.New(&buf, "", 0)})
+	s.Get("/u/:id", func(c *Context) error {
+		return c.Text(c.URL("u") + "," + c.URL("u", 7))
+	}).Name("u")
+
+	rc := &fasthttp.RequestCtx{}
+	rc.Request.SetRequestURI("/u/1")
+	s.GetHandler()(rc)
+
+	if got := rc.Response.StatusCode(); got != StatusOK {
+		t.Errorf("expected status %d, got %d", StatusOK, got)
+	}
+	if got := string(rc.Response.Body()); got != ",/u/7" {
+		t.Errorf("expected body %q, got %q", ",/u/7", got)
+	}
+	if got := buf.String(); !strings.Contains(got, "cannot build URL") || !strings.Contains(got, "path=/u/1") {
+		t.Errorf("expected the error to be logged with the request, got %q", got)
+	}
+}
+
+func TestResource(t *testing.T) {
+	s := New()
+	s.Post("/items", func(c *Context) error {
+		c.Paginate(30)
+		return c.Resource(StatusCreated, []string{"a", "b"})
+	})
+
+	rc := &fasthttp.RequestCtx{}
+	rc.Request.Header.SetMethod("POST")
+	rc.Request.SetRequestURI("/items?per_page=10&p=2")
+	s.GetHandler()(rc)
+
+	if got := rc.Response.StatusCode(); got != StatusCreated {
+		t.Errorf("expected status %d, got %d", StatusCreated, got)
+	}
+	var doc struct {
+		Links map[string]struct {
+			Href string `json:"href"`
+		} `json:"_links"`
+	}
+	if err := json.Unmarshal(rc.Response.Body(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"self":  "/items?per_page=10&p=2",
+		"first": "/items?per_page=10",
+		"prev":  "/items?per_page=10",
+		"next":  "/items?p=3&per_page=10",
+		"last":  "/items?p=3&per_page=10",
+	}
+	if len(doc.Links) != len(want) {
+		t.Errorf("expected links %v, got %v", want, doc.Links)
+	}
+	for rel, href := range want {
+		if got := doc.Links[rel].Href; got != href {
+			t.Errorf("expected %s link %q, got %q", rel, href, got)
+		}
+	}
+}
+
+func TestResourceHAL(t *testing.T) {
+	type user struct {
+		ID    int64   `json:"id"`
+		Score float64 `json:"score"`
+	}
+
+	tests := []struct {
+		name string
+		v    interface{}
+		want []string
+	}{
+		{name: "int64", v: user{ID: 9007199254740993, Score: 1.5}, want: []string{`"id":9007199254740993`, `"score":1.5`}},
+		{name: "map", v: map[string]interface{}{"id": int64(-9007199254740993)}, want: []string{`"id":-9007199254740993`}},
+		{name: "not an object", v: "a", want: []string{`"_embedded":{"items":"a"}`}},
+		{name: "collection", v: []int64{9007199254740993}, want: []string{`"items":[9007199254740993]`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.Get("/users/1", func(c *Context) error {
+				return c.Resource(StatusOK, tt.v)
+			})
+
+			rc := &fasthttp.RequestCtx{}
+			rc.Request.SetRequestURI("/users/1")
+			s.GetHandler()(rc)
+
+			body := string(rc.Response.Body())
+			for _, want := range append(tt.want, `"_links":{"self":{"href":"/users/1","method":"GET"}}`) {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected body to contain %s, got %s", want, body)
+				}
+			}
+		})
+	}
+}
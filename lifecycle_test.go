@@ -0,0 +1,127 @@
+package valse
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	tests := []struct {
+		name string
+		// block keeps Listen in its OnStart hooks until Shutdown is called.
+		block bool
+	}{
+		{name: "serving"},
+		{name: "starting", block: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			started := make(chan struct{})
+			release := make(chan struct{})
+			s.OnStart(func() error {
+				close(started)
+				if tt.block {
+					<-release
+				}
+				return nil
+			})
+			var shutdown int
+			s.OnShutdown(func() error {
+				shutdown++
+				return nil
+			})
+
+			listened := make(chan error, 1)
+			go func() {
+				listened <- s.Listen("127.0.0.1:0")
+			}()
+			<-started
+			if !tt.block {
+				// Give Listen time to reach Serve.
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			errc := make(chan error, 1)
+			go func() {
+				errc <- s.Shutdown(ctx)
+			}()
+			if tt.block {
+				time.Sleep(50 * time.Millisecond)
+				close(release)
+			}
+
+			if err := <-errc; err != nil {
+				t.Fatalf("Shutdown: %v", err)
+			}
+			if shutdown != 1 {
+				t.Errorf("expected the shutdown hook to run once, ran %d times", shutdown)
+			}
+			select {
+			case <-listened:
+			case <-time.After(time.Second):
+				t.Fatal("Listen did not return")
+			}
+			if err := s.Shutdown(ctx); err == nil {
+				t.Error("expected an error shutting down a stopped server")
+			}
+		})
+	}
+}
+
+func TestShutdownRetry(t *testing.T) {
+	s := New()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s.OnStart(func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	var shutdown int
+	s.OnShutdown(func() error {
+		shutdown++
+		return nil
+	})
+
+	listened := make(chan error, 1)
+	go func() {
+		listened <- s.Listen("127.0.0.1:0")
+	}()
+	<-started
+
+	// Listen is still starting when the first Shutdown gives up.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if shutdown != 0 {
+		t.Errorf("expected the shutdown hook not to run, ran %d times", shutdown)
+	}
+	close(release)
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case err := <-listened:
+		t.Fatalf("expected the server to keep serving, Listen returned %v", err)
+	default:
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if shutdown != 1 {
+		t.Errorf("expected the shutdown hook to run once, ran %d times", shutdown)
+	}
+	select {
+	case <-listened:
+	case <-time.After(time.Second):
+		t.Fatal("Listen did not return")
+	}
+}
@@ -1,5 +1,13 @@
 package valse
 
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
 type Logger interface {
 	Print(...interface{})
 	Printf(string, ...interface{})
@@ -13,3 +21,157 @@ type Logger interface {
 	Panicf(string, ...interface{})
 	Panicln(...interface{})
 }
+
+// Fields are the structured fields attached to log entries.
+type Fields map[string]interface{}
+
+// FieldLogger is a leveled logger with structured fields.
+type FieldLogger interface {
+	Logger
+
+	WithField(key string, value interface{}) FieldLogger
+	WithFields(fields Fields) FieldLogger
+	WithError(err error) FieldLogger
+
+	Debug(...interface{})
+	Debugf(string, ...interface{})
+	Info(...interface{})
+	Infof(string, ...interface{})
+	Warn(...interface{})
+	Warnf(string, ...interface{})
+	Error(...interface{})
+	Errorf(string, ...interface{})
+}
+
+// NewFieldLogger returns l if it is a FieldLogger, and otherwise wraps it in
+// one which writes the level and fields through l.Printf. A nil l logs to
+// stderr with the standard log package.
+func NewFieldLogger(l Logger) FieldLogger {
+	if l == nil {
+		l = log.New(os.Stderr, "", log.LstdFlags)
+	}
+	if fl, ok := l.(FieldLogger); ok {
+		return fl
+	}
+	return &fieldLogger{l: l}
+}
+
+type fieldLogger struct {
+	l      Logger
+	fields Fields
+}
+
+func (f *fieldLogger) WithField(key string, value interface{}) FieldLogger {
+	return f.WithFields(Fields{key: value})
+}
+
+func (f *fieldLogger) WithFields(fields Fields) FieldLogger {
+	out := make(Fields, len(f.fields)+len(fields))
+	for k, v := range f.fields {
+		out[k] = v
+	}
+	for k, v := range fields {
+		out[k] = v
+	}
+	return &fieldLogger{l: f.l, fields: out}
+}
+
+func (f *fieldLogger) WithError(err error) FieldLogger {
+	return f.WithField("error", err)
+}
+
+func (f *fieldLogger) log(level, msg string) {
+	if len(f.fields) == 0 {
+		f.l.Printf("[%s] %s", level, msg)
+		return
+	}
+
+	keys := make([]string, 0, len(f.fields))
+	for k := range f.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%v", k, f.fields[k])
+	}
+	f.l.Printf("[%s] %s %s", level, msg, strings.Join(parts, " "))
+}
+
+func (f *fieldLogger) Debug(args ...interface{}) {
+	f.log("DEBUG", fmt.Sprint(args...))
+}
+
+func (f *fieldLogger) Debugf(format string, args ...interface{}) {
+	f.log("DEBUG", fmt.Sprintf(format, args...))
+}
+
+func (f *fieldLogger) Info(args ...interface{}) {
+	f.log("INFO", fmt.Sprint(args...))
+}
+
+func (f *fieldLogger) Infof(format string, args ...interface{}) {
+	f.log("INFO", fmt.Sprintf(format, args...))
+}
+
+func (f *fieldLogger) Warn(args ...interface{}) {
+	f.log("WARN", fmt.Sprint(args...))
+}
+
+func (f *fieldLogger) Warnf(format string, args ...interface{}) {
+	f.log("WARN", fmt.Sprintf(format, args...))
+}
+
+func (f *fieldLogger) Error(args ...interface{}) {
+	f.log("ERROR", fmt.Sprint(args...))
+}
+
+func (f *fieldLogger) Errorf(format string, args ...interface{}) {
+	f.log("ERROR", fmt.Sprintf(format, args...))
+}
+
+func (f *fieldLogger) Print(args ...interface{}) {
+	f.log("INFO", fmt.Sprint(args...))
+}
+
+func (f *fieldLogger) Printf(format string, args ...interface{}) {
+	f.log("INFO", fmt.Sprintf(format, args...))
+}
+
+func (f *fieldLogger) Println(args ...interface{}) {
+	f.log("INFO", fmt.Sprintln(args...))
+}
+
+func (f *fieldLogger) Fatal(args ...interface{}) {
+	f.log("FATAL", fmt.Sprint(args...))
+	os.Exit(1)
+}
+
+func (f *fieldLogger) Fatalf(format string, args ...interface{}) {
+	f.log("FATAL", fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+func (f *fieldLogger) Fatalln(args ...interface{}) {
+	f.log("FATAL", fmt.Sprintln(args...))
+	os.Exit(1)
+}
+
+func (f *fieldLogger) Panic(args ...interface{}) {
+	s := fmt.Sprint(args...)
+	f.log("PANIC", s)
+	panic(s)
+}
+
+func (f *fieldLogger) Panicf(format string, args ...interface{}) {
+	s := fmt.Sprintf(format, args...)
+	f.log("PANIC", s)
+	panic(s)
+}
+
+func (f *fieldLogger) Panicln(args ...interface{}) {
+	s := fmt.Sprintln(args...)
+	f.log("PANIC", s)
+	panic(s)
+}
@@ -5,68 +5,326 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/valyala/fasthttp"
 	"github.com/xwinie/valse"
 )
 
+// Headers carrying the signature and the signed values.
+const (
+	HeaderAppID     = "appid"
+	HeaderTimestamp = "timestamp"
+	HeaderNonce     = "nonce"
+	HeaderSignature = "signature"
+)
+
+// TimestampLayout is the legacy timestamp format, read in local time. Unix
+// seconds are accepted as well and are what Signer sends.
+const TimestampLayout = "2006-01-02 15:04:05"
+
+const maxNonceLength = 128
+
+// appIDKey stores the verified appid, see AppIDFrom.
+const appIDKey = "valse.apisign.appid"
+
 //Config 需要的结构配置
 type Config struct {
-	f       func(string) string
-	timeout int
+	// Skipper defines a function to skip middleware.
+	// Optional. Default value valse.DefaultSkipper.
+	Skipper valse.Skipper
+
+	// Secret returns the secret of appid, or an empty string for unknown
+	// appids.
+	// Required.
+	Secret func(appid string) string
+
+	// MaxSkew is how far the request timestamp may be behind or ahead of
+	// the server clock.
+	// Optional. Default value 5 minutes.
+	MaxSkew time.Duration `json:"max_skew"`
+
+	// NonceStore rejects nonces which were already used within MaxSkew.
+	// Optional. Default value a MemoryNonceStore.
+	NonceStore NonceStore
+
+	// SignedHeaders are signed in addition to the appid, timestamp and
+	// nonce headers, e.g. "Content-Type". Clients must sign the same
+	// headers, see Signer.SignedHeaders.
+	// Optional.
+	SignedHeaders []string `json:"signed_headers"`
+
+	// Legacy also accepts requests without a nonce header signed the way
+	// earlier versions did, see Signature, so existing clients keep working
+	// while they move to Signer. Legacy requests are not protected against
+	// replays within MaxSkew.
+	// Optional. Default value false.
+	Legacy bool `json:"legacy"`
 }
 
-//APISignAuth api sign auth
-func APISignAuth(c Config, next valse.RequestHandler) valse.RequestHandler {
-	return func(ctx *valse.Context) error {
-		if string(ctx.HeaderParameter("appid")) == "" {
-			return valse.NewHTTPMessage(http.StatusForbidden, "miss appid header")
-		}
-		appsecret := c.f(string(ctx.HeaderParameter("appid")))
-		if appsecret == "" {
-			return valse.NewHTTPMessage(fasthttp.StatusForbidden, "not exist this appid")
-		}
-		clientSignature := string(ctx.HeaderParameter("signature"))
-		if clientSignature == "" {
-			return valse.NewHTTPMessage(fasthttp.StatusForbidden, "miss signature header")
-		}
-		if string(ctx.HeaderParameter("timestamp")) == "" {
-			return valse.NewHTTPMessage(fasthttp.StatusForbidden, "miss timestamp header")
-		}
-		u, err := time.Parse("2006-01-02 15:04:05", string(ctx.HeaderParameter("timestamp")))
-		if err != nil {
+var (
+	// DefaultConfig is the default APISignAuth middleware config.
+	DefaultConfig = Config{
+		Skipper: valse.DefaultSkipper,
+		MaxSkew: 5 * time.Minute,
+	}
+)
 
-			return valse.NewHTTPMessage(fasthttp.StatusForbidden, "timestamp format is error, should 2006-01-02 15:04:05")
-		}
-		t := time.Now()
-		if t.Sub(u).Seconds() > float64(c.timeout) {
-			return valse.NewHTTPMessage(fasthttp.StatusForbidden, "timeout! the request time is long ago, please try again")
-		}
-		var requestURL string
-		var boy []byte
-		if ctx.IsGet() {
-			requestURL = string(bytes.TrimLeft(ctx.RequestURI(), "/"))
-		} else {
-			boy = ctx.GetBody()
+// NewConfig returns DefaultConfig with the given secret lookup.
+func NewConfig(secret func(appid string) string) Config {
+	c := DefaultConfig
+	c.Secret = secret
+	return c
+}
+
+// AppIDFrom returns the appid verified by the APISignAuth middleware.
+func AppIDFrom(c *valse.Context) string {
+	appid, _ := c.UserValue(appIDKey).(string)
+	return appid
+}
+
+// APISignAuth returns a middleware verifying signed requests, see
+// CanonicalRequest for what is signed.
+//
+// Requests must send the appid, timestamp, nonce and signature headers. The
+// signature is the base64 encoded HMAC-SHA256 of the canonical request with
+// the secret of the appid. Requests with a timestamp more than MaxSkew away
+// from the server clock, or with a nonce used before, are rejected with
+// "403 - Forbidden". See Config.Legacy for requests signed with Signature.
+func APISignAuth(secret func(appid string) string) valse.MiddlewareHandler {
+	return APISignAuthWithConfig(NewConfig(secret))
+}
+
+// APISignAuthWithConfig returns an APISignAuth middleware with config.
+// See: `APISignAuth()`.
+func APISignAuthWithConfig(config Config) valse.MiddlewareHandler {
+	// Defaults
+	if config.Secret == nil {
+		panic("apisignauth middleware requires secret function")
+	}
+	if config.Skipper == nil {
+		config.Skipper = DefaultConfig.Skipper
+	}
+	if config.MaxSkew == 0 {
+		config.MaxSkew = DefaultConfig.MaxSkew
+	}
+	if config.NonceStore == nil {
+		config.NonceStore = NewMemoryNonceStore()
+	}
+	signedHeaders := canonicalHeaderNames(config.SignedHeaders)
+
+	return func(next valse.RequestHandler) valse.RequestHandler {
+		return func(ctx *valse.Context) error {
+			if config.Skipper(ctx) {
+				return next(ctx)
+			}
+
+			appid := string(ctx.HeaderParameter(HeaderAppID))
+			if appid == "" {
+				return valse.NewHTTPMessage(http.StatusForbidden, "miss appid header")
+			}
+			appsecret := config.Secret(appid)
+			if appsecret == "" {
+				return valse.NewHTTPMessage(fasthttp.StatusForbidden, "not exist this appid")
+			}
+			clientSignature := string(ctx.HeaderParameter(HeaderSignature))
+			if clientSignature == "" {
+				return valse.NewHTTPMessage(fasthttp.StatusForbidden, "miss signature header")
+			}
+			timestamp := string(ctx.HeaderParameter(HeaderTimestamp))
+			if timestamp == "" {
+				return valse.NewHTTPMessage(fasthttp.StatusForbidden, "miss timestamp header")
+			}
+			nonce := string(ctx.HeaderParameter(HeaderNonce))
+			if nonce == "" && config.Legacy {
+				if err := verifyLegacy(ctx, appsecret, clientSignature, timestamp, config.MaxSkew); err != nil {
+					return err
+				}
+				ctx.SetUserValue(appIDKey, appid)
+				return next(ctx)
+			}
+			if nonce == "" {
+				return valse.NewHTTPMessage(fasthttp.StatusForbidden, "miss nonce header")
+			}
+			if len(nonce) > maxNonceLength {
+				return valse.NewHTTPMessage(fasthttp.StatusForbidden, "nonce is too long")
+			}
+
+			u, err := ParseTimestamp(timestamp)
+			if err != nil {
+				return valse.NewHTTPMessage(fasthttp.StatusForbidden, "timestamp format is error, should be unix seconds or 2006-01-02 15:04:05")
+			}
+			if skew := time.Since(u); skew > config.MaxSkew || skew < -config.MaxSkew {
+				return valse.NewHTTPMessage(fasthttp.StatusForbidden, "timeout! the request time is too far from the server time, please try again")
+			}
+
+			headers := make(map[string]string, len(signedHeaders))
+			for _, h := range signedHeaders {
+				headers[h] = string(ctx.Request.Header.Peek(h))
+			}
+			query := url.Values{}
+			ctx.QueryArgs().VisitAll(func(k, v []byte) {
+				query.Add(string(k), string(v))
+			})
+			canonical := CanonicalRequest(string(ctx.Method()), string(ctx.URI().PathOriginal()), query, headers, ctx.GetBody())
+			if !hmac.Equal([]byte(clientSignature), []byte(Sign(appsecret, canonical))) {
+				return valse.NewHTTPMessage(fasthttp.StatusForbidden, "Signature Failed")
+			}
+
+			// Only signed requests use up nonces.
+			fresh, err := config.NonceStore.Use(appid, nonce, u.Add(config.MaxSkew))
+			if err != nil {
+				return err
+			}
+			if !fresh {
+				return valse.NewHTTPMessage(fasthttp.StatusForbidden, "nonce has been used")
+			}
+
+			ctx.SetUserValue(appIDKey, appid)
+			return next(ctx)
 		}
-		serviceSignature := Signature(appsecret, string(ctx.Method()), boy, requestURL, string(ctx.HeaderParameter("timestamp")))
-		if clientSignature != serviceSignature {
-			return valse.NewHTTPMessage(fasthttp.StatusForbidden, "Signature Failed")
+	}
+}
+
+// verifyLegacy verifies a request signed with Signature. As in earlier
+// versions, the timestamp is in TimestampLayout and UTC, and only rejected
+// when it is more than maxSkew in the past.
+func verifyLegacy(ctx *valse.Context, appsecret, clientSignature, timestamp string, maxSkew time.Duration) error {
+	u, err := time.Parse(TimestampLayout, timestamp)
+	if err != nil {
+		return valse.NewHTTPMessage(fasthttp.StatusForbidden, "timestamp format is error, should 2006-01-02 15:04:05")
+	}
+	if time.Since(u) > maxSkew {
+		return valse.NewHTTPMessage(fasthttp.StatusForbidden, "timeout! the request time is long ago, please try again")
+	}
+
+	var requestURL string
+	var body []byte
+	if ctx.IsGet() {
+		requestURL = string(bytes.TrimLeft(ctx.RequestURI(), "/"))
+	} else {
+		body = ctx.GetBody()
+	}
+	serviceSignature := Signature(appsecret, string(ctx.Method()), body, requestURL, timestamp)
+	if !hmac.Equal([]byte(clientSignature), []byte(serviceSignature)) {
+		return valse.NewHTTPMessage(fasthttp.StatusForbidden, "Signature Failed")
+	}
+	return nil
+}
+
+// ParseTimestamp parses a timestamp header, in unix seconds or in
+// TimestampLayout.
+func ParseTimestamp(s string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.ParseInLocation(TimestampLayout, s, time.Local)
+}
+
+// CanonicalRequest returns the string which is signed for a request:
+//
+//	METHOD
+//	PATH
+//	QUERY
+//	HEADERS
+//	SIGNED HEADERS
+//	BODY HASH
+//
+// PATH is the escaped path as sent. QUERY are the query parameters sorted by
+// name and value, escaped with url.QueryEscape and joined as
+// "a=1&b=2&b=3". HEADERS are the signed headers sorted by lowercase name, one
+// "name:value" line each with the value trimmed, and SIGNED HEADERS their
+// names joined by ";". BODY HASH is the hex encoded SHA-256 of the body.
+// headers maps the names of the signed headers to their values.
+func CanonicalRequest(method, path string, query url.Values, headers map[string]string, body []byte) string {
+	var b strings.Builder
+
+	b.WriteString(strings.ToUpper(method))
+	b.WriteByte('\n')
+	if path == "" {
+		path = "/"
+	}
+	b.WriteString(path)
+	b.WriteByte('\n')
+
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	first := true
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			if !first {
+				b.WriteByte('&')
+			}
+			first = false
+			b.WriteString(url.QueryEscape(k))
+			b.WriteByte('=')
+			b.WriteString(url.QueryEscape(v))
 		}
-		return next(ctx)
 	}
+	b.WriteByte('\n')
 
+	names := make([]string, 0, len(headers))
+	values := make(map[string]string, len(headers))
+	for k, v := range headers {
+		k = strings.ToLower(k)
+		names = append(names, k)
+		values[k] = strings.TrimSpace(v)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		b.WriteString(k)
+		b.WriteByte(':')
+		b.WriteString(values[k])
+		b.WriteByte('\n')
+	}
+	b.WriteString(strings.Join(names, ";"))
+	b.WriteByte('\n')
+
+	sum := sha256.Sum256(body)
+	b.WriteString(hex.EncodeToString(sum[:]))
+	return b.String()
+}
+
+// Sign returns the base64 encoded HMAC-SHA256 of canonicalRequest.
+func Sign(appSecret, canonicalRequest string) string {
+	hash := hmac.New(sha256.New, []byte(appSecret))
+	hash.Write([]byte(canonicalRequest))
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
 }
 
 // Signature used to generate signature with the appsecret/method/params/RequestURI
+//
+// Deprecated: APISignAuth verifies signatures of the canonical request, see
+// CanonicalRequest and Sign. Signature is only accepted with Config.Legacy.
 func Signature(appSecret, method string, body []byte, RequestURL string, timestamp string) (result string) {
 	stringToSign := fmt.Sprintf("%v\n%v\n%v\n%v\n", method, string(body), RequestURL, timestamp)
-	fmt.Println(stringToSign)
-	sha256 := sha256.New
-	hash := hmac.New(sha256, []byte(appSecret))
-	hash.Write([]byte(stringToSign))
-	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
+	return Sign(appSecret, stringToSign)
+}
+
+// canonicalHeaderNames returns the lowercase names of the always signed
+// headers and extra, without duplicates.
+func canonicalHeaderNames(extra []string) []string {
+	names := []string{HeaderAppID, HeaderTimestamp, HeaderNonce}
+	seen := map[string]bool{HeaderAppID: true, HeaderTimestamp: true, HeaderNonce: true}
+	for _, h := range extra {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" || h == HeaderSignature || seen[h] {
+			continue
+		}
+		seen[h] = true
+		names = append(names, h)
+	}
+	return names
 }
@@ -0,0 +1,193 @@
+package apisignauth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"github.com/xwinie/valse"
+)
+
+func secrets(appid string) string {
+	if appid == "app" {
+		return "secret"
+	}
+	return ""
+}
+
+func signedRequest(t *testing.T, signer *Signer, method, uri, body string) *fasthttp.Request {
+	req := &fasthttp.Request{}
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	req.SetBodyString(body)
+	req.Header.Set("Content-Type", "application/json")
+	if err := signer.SignFastHTTP(req); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func legacyRequest(method, uri, body string, at time.Time) *fasthttp.Request {
+	req := &fasthttp.Request{}
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	req.SetBodyString(body)
+	timestamp := at.UTC().Format(TimestampLayout)
+	var signedURL string
+	var signedBody []byte
+	if method == "GET" {
+		signedURL = string(req.RequestURI()[1:])
+	} else {
+		signedBody = []byte(body)
+	}
+	req.Header.Set(HeaderAppID, "app")
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Signature("secret", method, signedBody, signedURL, timestamp))
+	return req
+}
+
+func TestAPISignAuth(t *testing.T) {
+	signer := NewSigner("app", "secret")
+	signer.SignedHeaders = []string{"Content-Type"}
+	replayed := signedRequest(t, signer, "POST", "/orders?b=2&a=1", `{"id":1}`)
+
+	tests := []struct {
+		name   string
+		legacy bool
+		req    func() *fasthttp.Request
+		// twice sends the request twice, checking the second response.
+		twice bool
+		want  bool
+	}{
+		{
+			name: "signed",
+			req:  func() *fasthttp.Request { return signedRequest(t, signer, "POST", "/orders?b=2&a=1", `{"id":1}`) },
+			want: true,
+		},
+		{
+			name: "signed get",
+			req:  func() *fasthttp.Request { return signedRequest(t, signer, "GET", "/orders?q=a%20b", "") },
+			want: true,
+		},
+		{
+			name:  "replayed",
+			req:   func() *fasthttp.Request { return replayed },
+			twice: true,
+		},
+		{
+			name: "tampered body",
+			req: func() *fasthttp.Request {
+				req := signedRequest(t, signer, "POST", "/orders", `{"id":1}`)
+				req.SetBodyString(`{"id":2}`)
+				return req
+			},
+		},
+		{
+			name: "tampered query",
+			req: func() *fasthttp.Request {
+				req := signedRequest(t, signer, "GET", "/orders?id=1", "")
+				req.SetRequestURI("/orders?id=2")
+				return req
+			},
+		},
+		{
+			name: "tampered signed header",
+			req: func() *fasthttp.Request {
+				req := signedRequest(t, signer, "POST", "/orders", `{"id":1}`)
+				req.Header.Set("Content-Type", "text/plain")
+				return req
+			},
+		},
+		{
+			name: "stale",
+			req: func() *fasthttp.Request {
+				s := *signer
+				s.Now = func() time.Time { return time.Now().Add(-time.Hour) }
+				return signedRequest(t, &s, "GET", "/orders", "")
+			},
+		},
+		{
+			name: "unknown appid",
+			req: func() *fasthttp.Request {
+				s := *signer
+				s.AppID = "other"
+				return signedRequest(t, &s, "GET", "/orders", "")
+			},
+		},
+		{
+			name: "legacy disabled",
+			req:  func() *fasthttp.Request { return legacyRequest("POST", "/orders", `{"id":1}`, time.Now()) },
+		},
+		{
+			name:   "legacy post",
+			legacy: true,
+			req:    func() *fasthttp.Request { return legacyRequest("POST", "/orders", `{"id":1}`, time.Now()) },
+			want:   true,
+		},
+		{
+			name:   "legacy get",
+			legacy: true,
+			req:    func() *fasthttp.Request { return legacyRequest("GET", "/orders?id=1", "", time.Now()) },
+			want:   true,
+		},
+		{
+			name:   "legacy stale",
+			legacy: true,
+			req:    func() *fasthttp.Request { return legacyRequest("GET", "/orders", "", time.Now().Add(-time.Hour)) },
+		},
+		{
+			name:   "legacy tampered",
+			legacy: true,
+			req: func() *fasthttp.Request {
+				req := legacyRequest("POST", "/orders", `{"id":1}`, time.Now())
+				req.SetBodyString(`{"id":2}`)
+				return req
+			},
+		},
+		{
+			name:   "signed with legacy enabled",
+			legacy: true,
+			req:    func() *fasthttp.Request { return signedRequest(t, signer, "POST", "/orders", `{"id":1}`) },
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig(secrets)
+			config.SignedHeaders = []string{"content-type"}
+			config.Legacy = tt.legacy
+
+			var appid string
+			s := valse.New()
+			s.Use(APISignAuthWithConfig(config))
+			handler := func(c *valse.Context) error {
+				appid = AppIDFrom(c)
+				return nil
+			}
+			s.Get("/orders", handler)
+			s.Post("/orders", handler)
+			h := s.GetHandler()
+
+			req := tt.req()
+			send := func() int {
+				appid = ""
+				rc := &fasthttp.RequestCtx{}
+				req.CopyTo(&rc.Request)
+				h(rc)
+				return rc.Response.StatusCode()
+			}
+			status := send()
+			if tt.twice {
+				status = send()
+			}
+
+			if tt.want && (status != fasthttp.StatusOK || appid != "app") {
+				t.Errorf("expected the request to pass, got status %d and appid %q", status, appid)
+			}
+			if !tt.want && (status != fasthttp.StatusForbidden || appid != "") {
+				t.Errorf("expected 403, got status %d and appid %q", status, appid)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package apisignauth
+
+import (
+	"sync"
+	"time"
+)
+
+// NonceStore remembers the nonces of verified requests, so a signed request
+// cannot be replayed.
+type NonceStore interface {
+	// Use records nonce for appid until the given time, after which the
+	// request's timestamp is rejected anyway. It returns false if the nonce
+	// was already used.
+	Use(appid, nonce string, until time.Time) (bool, error)
+}
+
+// MemoryNonceStore is a NonceStore kept in memory. Nonces are not shared
+// between instances, use a shared store when running more than one.
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	pruned time.Time
+}
+
+// NewMemoryNonceStore returns an empty in-memory nonce store.
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[string]time.Time)}
+}
+
+// Use implements NonceStore.
+func (m *MemoryNonceStore) Use(appid, nonce string, until time.Time) (bool, error) {
+	key := appid + "\x00" + nonce
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.Sub(m.pruned) > time.Minute {
+		m.prune(now)
+	}
+	if exp, ok := m.nonces[key]; ok && now.Before(exp) {
+		return false, nil
+	}
+	m.nonces[key] = until
+	return true, nil
+}
+
+// prune drops expired nonces. The caller must hold the lock.
+func (m *MemoryNonceStore) prune(now time.Time) {
+	for key, until := range m.nonces {
+		if !now.Before(until) {
+			delete(m.nonces, key)
+		}
+	}
+	m.pruned = now
+}
@@ -0,0 +1,110 @@
+package apisignauth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Signer signs requests for the APISignAuth middleware.
+//
+//	signer := apisignauth.NewSigner("my-app", "my-secret")
+//	req, _ := http.NewRequest("POST", "https://api.example.com/orders?x=1", body)
+//	if err := signer.Sign(req); err != nil {
+//		return err
+//	}
+//	res, err := http.DefaultClient.Do(req)
+type Signer struct {
+	// AppID is sent in the appid header.
+	AppID string
+	// Secret is the secret of AppID.
+	Secret string
+	// SignedHeaders must equal Config.SignedHeaders of the server.
+	SignedHeaders []string
+	// Now returns the signing time.
+	// Optional. Default value time.Now.
+	Now func() time.Time
+}
+
+// NewSigner returns a Signer for appid.
+func NewSigner(appid, secret string) *Signer {
+	return &Signer{AppID: appid, Secret: secret}
+}
+
+// Sign sets the appid, timestamp, nonce and signature headers of req. The
+// body is read and replaced, so it can still be sent.
+func (s *Signer) Sign(req *http.Request) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	if err := s.setHeaders(req.Header.Set); err != nil {
+		return err
+	}
+	headers := make(map[string]string)
+	for _, h := range canonicalHeaderNames(s.SignedHeaders) {
+		headers[h] = req.Header.Get(h)
+	}
+	if _, ok := headers["host"]; ok {
+		headers["host"] = req.Host
+		if headers["host"] == "" {
+			headers["host"] = req.URL.Host
+		}
+	}
+
+	canonical := CanonicalRequest(req.Method, req.URL.EscapedPath(), req.URL.Query(), headers, body)
+	req.Header.Set(HeaderSignature, Sign(s.Secret, canonical))
+	return nil
+}
+
+// SignFastHTTP is Sign for fasthttp requests.
+func (s *Signer) SignFastHTTP(req *fasthttp.Request) error {
+	if err := s.setHeaders(req.Header.Set); err != nil {
+		return err
+	}
+	headers := make(map[string]string)
+	for _, h := range canonicalHeaderNames(s.SignedHeaders) {
+		headers[h] = string(req.Header.Peek(h))
+	}
+
+	query := url.Values{}
+	req.URI().QueryArgs().VisitAll(func(k, v []byte) {
+		query.Add(string(k), string(v))
+	})
+	canonical := CanonicalRequest(string(req.Header.Method()), string(req.URI().PathOriginal()), query, headers, req.Body())
+	req.Header.Set(HeaderSignature, Sign(s.Secret, canonical))
+	return nil
+}
+
+func (s *Signer) setHeaders(set func(key, value string)) error {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	set(HeaderAppID, s.AppID)
+	set(HeaderTimestamp, strconv.FormatInt(now().Unix(), 10))
+	set(HeaderNonce, hex.EncodeToString(nonce))
+	return nil
+}
@@ -0,0 +1,119 @@
+package casbin
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/casbin/casbin/model"
+	"github.com/casbin/casbin/persist"
+)
+
+// MemoryAdapter is a policy adapter kept in memory, holding policy lines in
+// the CSV format of policy files, e.g. "p, admin, /users/*, GET". Policies
+// added or removed through the Authorizer are saved to it.
+type MemoryAdapter struct {
+	mu    sync.RWMutex
+	lines []string
+}
+
+// NewMemoryAdapter returns an adapter holding the given policy lines.
+func NewMemoryAdapter(lines ...string) *MemoryAdapter {
+	a := &MemoryAdapter{}
+	a.SetPolicy(lines...)
+	return a
+}
+
+// SetPolicy replaces the policy lines. Call Authorizer.Reload to apply them.
+func (a *MemoryAdapter) SetPolicy(lines ...string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lines = a.lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		a.lines = append(a.lines, policyLine(fields[0], fields[1:]))
+	}
+}
+
+// LoadPolicy implements persist.Adapter.
+func (a *MemoryAdapter) LoadPolicy(m model.Model) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, line := range a.lines {
+		persist.LoadPolicyLine(line, m)
+	}
+	return nil
+}
+
+// SavePolicy implements persist.Adapter.
+func (a *MemoryAdapter) SavePolicy(m model.Model) error {
+	var lines []string
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range m[sec] {
+			for _, rule := range ast.Policy {
+				lines = append(lines, policyLine(ptype, rule))
+			}
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lines = lines
+	return nil
+}
+
+// AddPolicy implements persist.Adapter.
+func (a *MemoryAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lines = append(a.lines, policyLine(ptype, rule))
+	return nil
+}
+
+// RemovePolicy implements persist.Adapter.
+func (a *MemoryAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	line := policyLine(ptype, rule)
+	return a.remove(func(l string) bool { return l == line })
+}
+
+// RemoveFilteredPolicy implements persist.Adapter.
+func (a *MemoryAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	return a.remove(func(l string) bool {
+		fields := strings.Split(l, ", ")
+		if fields[0] != ptype {
+			return false
+		}
+		fields = fields[1:]
+		for i, v := range fieldValues {
+			if v == "" {
+				continue
+			}
+			if fieldIndex+i >= len(fields) || fields[fieldIndex+i] != v {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (a *MemoryAdapter) remove(match func(string) bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	lines := a.lines[:0]
+	for _, l := range a.lines {
+		if !match(l) {
+			lines = append(lines, l)
+		}
+	}
+	a.lines = lines
+	return nil
+}
+
+func policyLine(ptype string, rule []string) string {
+	return ptype + ", " + strings.Join(rule, ", ")
+}
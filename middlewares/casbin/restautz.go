@@ -3,19 +3,108 @@ package casbin
 import (
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/casbin/casbin"
+	"github.com/casbin/casbin/persist"
+	fileadapter "github.com/casbin/casbin/persist/file-adapter"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/xwinie/valse"
+	"github.com/xwinie/valse/middlewares/apisignauth"
+	valsejwt "github.com/xwinie/valse/middlewares/jwt"
 )
 
+// DefaultModel is a RESTful RBAC model: policies are "p, <subject or role>,
+// <path pattern>, <method or *>" and roles "g, <user>, <role>". Paths are
+// matched with keyMatch2, so "/users/:id" and "/users/*" are valid patterns.
+const DefaultModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && keyMatch2(r.obj, p.obj) && (r.act == p.act || p.act == "*")
+`
+
+// DomainModel is DefaultModel with domains (tenants): policies are
+// "p, <role>, <domain>, <path pattern>, <method or *>" and roles
+// "g, <user>, <role>, <domain>". Use it with Config.Domain.
+const DomainModel = `
+[request_definition]
+r = sub, dom, obj, act
+
+[policy_definition]
+p = sub, dom, obj, act
+
+[role_definition]
+g = _, _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub, r.dom) && r.dom == p.dom && keyMatch2(r.obj, p.obj) && (r.act == p.act || p.act == "*")
+`
+
 //Config casbin需要的配置
 type Config struct {
-	model string
-	User  string
-	f     func(string) ([]Permission, error) //基于token的Authorization来进行数据获取
-	Open  bool                               // 0代表非开放 1代表开放接口
+	// Skipper defines a function to skip middleware, e.g. for open APIs.
+	// Optional. Default value valse.DefaultSkipper.
+	Skipper valse.Skipper
+
+	// Model is the text of the casbin model.
+	// Optional. Default value DefaultModel.
+	Model string `json:"model"`
+
+	// ModelFile is the path of the casbin model. It takes precedence over
+	// Model.
+	// Optional.
+	ModelFile string `json:"model_file"`
+
+	// PolicyFile is the path of a CSV policy file, loaded with the casbin
+	// file adapter.
+	// Optional.
+	PolicyFile string `json:"policy_file"`
+
+	// Adapter loads and saves the policy. It takes precedence over
+	// PolicyFile.
+	// Optional. Default value an empty MemoryAdapter, unless PolicyFile is set.
+	Adapter persist.Adapter
+
+	// ReloadInterval is how often PolicyFile is checked for changes. Changed
+	// files are loaded without restarting the server.
+	// Optional. Default value 0, which disables reloading.
+	ReloadInterval time.Duration `json:"reload_interval"`
+
+	// Logger reports policy files which fail to reload.
+	// Optional. Default value the standard logger.
+	Logger valse.Logger
+
+	// Subject returns the subject of the request. An empty subject is
+	// rejected with "401 - Unauthorized".
+	// Optional. Default value SubjectFromJWT.
+	Subject func(*valse.Context) string
+
+	// Domain returns the domain (tenant) of the request. If set, requests
+	// are enforced as (subject, domain, path, method), see DomainModel.
+	// Optional.
+	Domain func(*valse.Context) string
+
+	// CacheSize is the number of decisions cached. The cache is cleared
+	// whenever the policy changes.
+	// Optional. Default value 10000. A negative value disables the cache.
+	CacheSize int `json:"cache_size"`
 }
 
 // Permission 需要的权限结果集合
@@ -27,40 +116,319 @@ type Permission struct {
 
 //Authorizer 权限结构
 type Authorizer struct {
+	config   Config
+	mu       sync.RWMutex
 	enforcer *casbin.Enforcer
+	cache    map[string]bool
+	gen      uint64
+	modTime  time.Time
+	done     chan struct{}
+	once     sync.Once
 }
 
-//RestAuth api sign auth
-func RestAuth(c Config, next valse.RequestHandler) valse.RequestHandler {
-	return func(ctx *valse.Context) error {
-		if string(ctx.HeaderParameter("appid")) == "" {
-			return valse.NewHTTPMessage(http.StatusForbidden, "miss appid header")
-		}
-		var e = casbin.NewEnforcer(casbin.NewModel(c.model))
-		a := &Authorizer{enforcer: e}
+var (
+	// DefaultConfig is the default casbin middleware config.
+	DefaultConfig = Config{
+		Skipper:   valse.DefaultSkipper,
+		Model:     DefaultModel,
+		Subject:   SubjectFromJWT,
+		CacheSize: 10000,
+	}
+)
+
+// SubjectFromJWT returns the "sub" claim of the token verified by the jwt
+// middleware.
+func SubjectFromJWT(c *valse.Context) string {
+	return valsejwt.SubjectFrom(c)
+}
 
-		permissions, err := c.f(string(ctx.HeaderParameter("Authorization")))
-		if err != nil {
-			return valse.NewHTTPMessage(http.StatusForbidden, "Auth Fail")
+// SubjectFromClaim returns a Subject function reading the string claim name
+// of the token verified by the jwt middleware.
+func SubjectFromClaim(name string) func(*valse.Context) string {
+	return func(c *valse.Context) string {
+		claims, ok := valsejwt.MapClaimsFrom(c)
+		if !ok {
+			return ""
 		}
-		for _, v := range permissions {
-			e.AddPermissionForUser(c.User, v.Action, v.Method)
+		sub, _ := claims[name].(string)
+		return sub
+	}
+}
+
+// SubjectFromAppID returns the appid verified by the apisignauth middleware.
+func SubjectFromAppID(c *valse.Context) string {
+	return apisignauth.AppIDFrom(c)
+}
+
+// DomainFromHeader returns a Domain function reading header.
+func DomainFromHeader(header string) func(*valse.Context) string {
+	return func(c *valse.Context) string {
+		return string(c.HeaderParameter(header))
+	}
+}
+
+// DomainFromParam returns a Domain function reading the route parameter name.
+func DomainFromParam(name string) func(*valse.Context) string {
+	return func(c *valse.Context) string {
+		dom, _ := c.UserValue(name).(string)
+		return dom
+	}
+}
+
+// NewAuthorizer returns an Authorizer with config. The enforcer is built and
+// the policy loaded once, and shared by all requests.
+func NewAuthorizer(config Config) (*Authorizer, error) {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultConfig.Skipper
+	}
+	if config.Model == "" {
+		config.Model = DefaultConfig.Model
+	}
+	if config.Subject == nil {
+		config.Subject = DefaultConfig.Subject
+	}
+	if config.CacheSize == 0 {
+		config.CacheSize = DefaultConfig.CacheSize
+	}
+	if config.Logger == nil {
+		config.Logger = valse.NewFieldLogger(nil)
+	}
+	if config.Adapter == nil {
+		if config.PolicyFile != "" {
+			config.Adapter = fileadapter.NewAdapter(config.PolicyFile)
+		} else {
+			config.Adapter = NewMemoryAdapter()
 		}
-		if !c.Open && !a.CheckPermission(c.User, string(ctx.Method()), string(ctx.Path())) {
-			return valse.NewHTTPMessage(http.StatusForbidden, "Auth Fail")
-		} else if c.Open && a.CheckPermission(c.User, string(ctx.Method()), string(ctx.Path())) {
-			return next(ctx)
+	}
+
+	a := &Authorizer{
+		config: config,
+		cache:  make(map[string]bool),
+		done:   make(chan struct{}),
+	}
+	e, err := a.newEnforcer()
+	if err != nil {
+		return nil, err
+	}
+	a.enforcer = e
+	if config.PolicyFile != "" && config.ReloadInterval > 0 {
+		a.modTime = fileModTime(config.PolicyFile)
+		go a.watch()
+	}
+	return a, nil
+}
 
+// RestAuth returns a middleware enforcing the policy for the subject, path and
+// method of each request. Requests without a subject are rejected with
+// "401 - Unauthorized" and denied requests with "403 - Forbidden".
+// It panics if the Authorizer cannot be built, see NewAuthorizer.
+func RestAuth(config Config) valse.MiddlewareHandler {
+	a, err := NewAuthorizer(config)
+	if err != nil {
+		panic(fmt.Sprintf("casbin middleware: %v", err))
+	}
+	return a.RestAuth()
+}
+
+// RestAuth returns a middleware enforcing the policy of a.
+// See: `RestAuth()`.
+func (a *Authorizer) RestAuth() valse.MiddlewareHandler {
+	return func(next valse.RequestHandler) valse.RequestHandler {
+		return func(ctx *valse.Context) error {
+			if a.config.Skipper(ctx) {
+				return next(ctx)
+			}
+
+			user := a.config.Subject(ctx)
+			if user == "" {
+				return valse.NewHTTPMessage(http.StatusUnauthorized, "Auth Fail")
+			}
+
+			var (
+				ok  bool
+				err error
+			)
+			if a.config.Domain != nil {
+				ok, err = a.Enforce(user, a.config.Domain(ctx), string(ctx.Path()), string(ctx.Method()))
+			} else {
+				ok, err = a.Enforce(user, string(ctx.Path()), string(ctx.Method()))
+			}
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return valse.NewHTTPMessage(http.StatusForbidden, "Auth Fail")
+			}
+			return next(ctx)
 		}
-		return next(ctx)
+	}
+}
+
+// Enforce decides whether the request values, in the order of the model's
+// request definition, are allowed.
+func (a *Authorizer) Enforce(rvals ...string) (bool, error) {
+	key := strings.Join(rvals, "\x00")
+
+	a.mu.RLock()
+	ok, cached := a.cache[key]
+	a.mu.RUnlock()
+	if cached {
+		return ok, nil
 	}
 
+	a.mu.RLock()
+	gen := a.gen
+	ok, err := a.enforcer.EnforceSafe(toInterfaces(rvals)...)
+	a.mu.RUnlock()
+	if err != nil {
+		return false, err
+	}
+	if a.config.CacheSize > 0 {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		// Don't cache decisions made before a policy change.
+		if gen != a.gen {
+			return ok, nil
+		}
+		if len(a.cache) >= a.config.CacheSize {
+			a.cache = make(map[string]bool)
+		}
+		a.cache[key] = ok
+	}
+	return ok, nil
 }
 
 // CheckPermission checks the user/method/path combination from the request.
 // Returns true (permission granted) or false (permission forbidden)
 func (a *Authorizer) CheckPermission(user, method, path string) bool {
-	return a.enforcer.Enforce(user, path, method)
+	ok, _ := a.Enforce(user, path, method)
+	return ok
+}
+
+// CheckPermissionInDomain checks the user/domain/method/path combination,
+// see DomainModel.
+func (a *Authorizer) CheckPermissionInDomain(user, domain, method, path string) bool {
+	ok, _ := a.Enforce(user, domain, path, method)
+	return ok
+}
+
+// AddPermissions allows user the given permissions.
+func (a *Authorizer) AddPermissions(user string, permissions ...Permission) {
+	a.update(func(e *casbin.Enforcer) {
+		for _, p := range permissions {
+			e.AddPermissionForUser(user, p.Action, p.Method)
+		}
+	})
+}
+
+// AddPolicy adds a policy rule, e.g. ("admin", "/users/*", "GET").
+func (a *Authorizer) AddPolicy(params ...string) (added bool) {
+	a.update(func(e *casbin.Enforcer) { added = e.AddPolicy(toInterfaces(params)...) })
+	return added
+}
+
+// RemovePolicy removes a policy rule.
+func (a *Authorizer) RemovePolicy(params ...string) (removed bool) {
+	a.update(func(e *casbin.Enforcer) { removed = e.RemovePolicy(toInterfaces(params)...) })
+	return removed
+}
+
+// AddRole assigns role to user, within domain if the model has domains.
+func (a *Authorizer) AddRole(user, role string, domain ...string) (added bool) {
+	params := append([]string{user, role}, domain...)
+	a.update(func(e *casbin.Enforcer) { added = e.AddGroupingPolicy(toInterfaces(params)...) })
+	return added
+}
+
+// RemoveRole removes role from user, within domain if the model has domains.
+func (a *Authorizer) RemoveRole(user, role string, domain ...string) (removed bool) {
+	params := append([]string{user, role}, domain...)
+	a.update(func(e *casbin.Enforcer) { removed = e.RemoveGroupingPolicy(toInterfaces(params)...) })
+	return removed
+}
+
+// SavePolicy saves the current policy to the adapter.
+func (a *Authorizer) SavePolicy() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.enforcer.SavePolicy()
+}
+
+// Reload loads the policy from the adapter again. The policy is loaded into
+// a new enforcer, so the current policy is kept if loading fails.
+func (a *Authorizer) Reload() error {
+	e, err := a.newEnforcer()
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.enforcer = e
+	a.cache = make(map[string]bool)
+	a.gen++
+	return nil
+}
+
+// newEnforcer builds an enforcer, with its own model, and loads the policy.
+func (a *Authorizer) newEnforcer() (*casbin.Enforcer, error) {
+	var m interface{} = casbin.NewModel(a.config.Model)
+	if a.config.ModelFile != "" {
+		m = a.config.ModelFile
+	}
+	return casbin.NewEnforcerSafe(m, a.config.Adapter)
+}
+
+// Close stops reloading the policy file.
+func (a *Authorizer) Close() error {
+	a.once.Do(func() { close(a.done) })
+	return nil
+}
+
+// update changes the enforcer and clears the decision cache.
+func (a *Authorizer) update(fn func(e *casbin.Enforcer)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	fn(a.enforcer)
+	a.cache = make(map[string]bool)
+	a.gen++
+}
+
+func (a *Authorizer) watch() {
+	ticker := time.NewTicker(a.config.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+			mod := fileModTime(a.config.PolicyFile)
+			if mod.IsZero() || mod.Equal(a.modTime) {
+				continue
+			}
+			// A broken file keeps the previous policy, until it changes again.
+			a.modTime = mod
+			if err := a.Reload(); err != nil {
+				a.config.Logger.Printf("casbin: could not reload %s: %v", a.config.PolicyFile, err)
+			}
+		}
+	}
+}
+
+func fileModTime(path string) time.Time {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
+
+func toInterfaces(params []string) []interface{} {
+	out := make([]interface{}, len(params))
+	for i, p := range params {
+		out[i] = p
+	}
+	return out
 }
 
 //ParseToken 解析token
@@ -0,0 +1,168 @@
+package casbin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type request struct {
+	user, method, path string
+	want               bool
+}
+
+func checkRequests(t *testing.T, a *Authorizer, requests []request) {
+	t.Helper()
+	for _, r := range requests {
+		if got := a.CheckPermission(r.user, r.method, r.path); got != r.want {
+			t.Errorf("CheckPermission(%s, %s, %s) = %v, want %v", r.user, r.method, r.path, got, r.want)
+		}
+	}
+}
+
+func TestAuthorizerReload(t *testing.T) {
+	adapter := NewMemoryAdapter(
+		"p, admin, /users/*, GET",
+		"g, alice, admin",
+	)
+	a, err := NewAuthorizer(Config{Adapter: adapter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	steps := []struct {
+		name     string
+		policy   []string
+		requests []request
+	}{
+		{
+			name: "initial",
+			requests: []request{
+				{"alice", "GET", "/users/1", true},
+				{"alice", "POST", "/users/1", false},
+				{"bob", "GET", "/users/1", false},
+			},
+		},
+		{
+			name:   "changed",
+			policy: []string{"p, admin, /posts/:id, *", "g, alice, admin", "g, bob, admin"},
+			requests: []request{
+				{"alice", "GET", "/users/1", false},
+				{"alice", "POST", "/posts/1", true},
+				{"bob", "DELETE", "/posts/2", true},
+			},
+		},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if step.policy != nil {
+				adapter.SetPolicy(step.policy...)
+				if err := a.Reload(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			checkRequests(t, a, step.requests)
+		})
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for the watcher goroutine.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestAuthorizerWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "casbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "policy.csv")
+
+	write := func(lines ...string) {
+		if err := ioutil.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		// Make sure the modification time changes.
+		mod := time.Now().Add(time.Duration(len(lines)) * time.Second)
+		os.Chtimes(file, mod, mod)
+	}
+	write("p, admin, /users/*, GET", "g, alice, admin")
+
+	var logs syncBuffer
+	a, err := NewAuthorizer(Config{
+		PolicyFile:     file,
+		ReloadInterval: 10 * time.Millisecond,
+		Logger:         log.New(&logs, "", 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	steps := []struct {
+		name     string
+		policy   []string
+		requests []request
+		logged   bool
+	}{
+		{
+			name: "loaded",
+			requests: []request{
+				{"alice", "GET", "/users/1", true},
+				{"bob", "GET", "/users/1", false},
+			},
+		},
+		{
+			name:   "changed",
+			policy: []string{"p, admin, /users/*, GET", "g, alice, admin", "g, bob, admin"},
+			requests: []request{
+				{"alice", "GET", "/users/1", true},
+				{"bob", "GET", "/users/1", true},
+			},
+		},
+		{
+			name:   "broken keeps previous policy",
+			policy: []string{"p, admin, /posts/*, GET", "x, broken"},
+			requests: []request{
+				{"bob", "GET", "/users/1", true},
+				{"bob", "GET", "/posts/1", false},
+			},
+			logged: true,
+		},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if step.policy != nil {
+				write(step.policy...)
+				time.Sleep(100 * time.Millisecond)
+			}
+			checkRequests(t, a, step.requests)
+			if logged := strings.Contains(logs.String(), "could not reload"); logged != step.logged {
+				t.Errorf("expected reload failure logged %v, got %q", step.logged, logs.String())
+			}
+		})
+	}
+}
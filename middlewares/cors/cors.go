@@ -1,26 +1,50 @@
 package cors
 
 import (
+	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/kildevaeld/strong"
-	"github.com/kildevaeld/valse"
+	"github.com/xwinie/valse"
 )
 
 // Shamefully stolen from the echo framework https://github.com/labstack/echo
 
+// Private Network Access headers, see https://wicg.github.io/private-network-access/
+const (
+	HeaderAccessControlRequestPrivateNetwork = "Access-Control-Request-Private-Network"
+	HeaderAccessControlAllowPrivateNetwork   = "Access-Control-Allow-Private-Network"
+)
+
 type (
 	// CORSConfig defines the config for CORS middleware.
 	CORSConfig struct {
 		// Skipper defines a function to skip middleware.
-		//Skipper Skipper
+		// Optional. Default value valse.DefaultSkipper.
+		Skipper valse.Skipper
 
 		// AllowOrigin defines a list of origins that may access the resource.
-		// Optional. If request header `Origin` is set, value is []string{"<Origin>"}
-		// else []string{"*"}.
+		// Entries are exact origins ("https://example.com"), wildcard
+		// subdomains ("https://*.example.com", or "*.example.com" for any
+		// scheme) or "*" for any origin. Only the matching request origin is
+		// sent back.
+		// Optional. Default value []string{"*"}, unless AllowOriginPatterns or
+		// AllowOriginFunc are set.
 		AllowOrigins []string `json:"allow_origins"`
 
+		// AllowOriginPatterns defines a list of regular expressions matched
+		// against the whole request origin.
+		// Optional.
+		AllowOriginPatterns []string `json:"allow_origin_patterns"`
+
+		// AllowOriginFunc reports whether origin may access the resource. It
+		// is consulted for origins not allowed by AllowOrigins or
+		// AllowOriginPatterns.
+		// Optional.
+		AllowOriginFunc func(origin string) bool
+
 		// AllowMethods defines a list methods allowed when accessing the resource.
 		// This is used in response to a preflight request.
 		// Optional. Default value DefaultCORSConfig.AllowMethods.
@@ -34,10 +58,18 @@ type (
 		// AllowCredentials indicates whether or not the response to the request
 		// can be exposed when the credentials flag is true. When used as part of
 		// a response to a preflight request, this indicates whether or not the
-		// actual request can be made using credentials.
+		// actual request can be made using credentials. It cannot be combined
+		// with the "*" origin, which would let any website make credentialed
+		// requests.
 		// Optional. Default value false.
 		AllowCredentials bool `json:"allow_credentials"`
 
+		// AllowPrivateNetwork allows requests from public websites to this
+		// server in a private network, by answering preflight requests
+		// carrying "Access-Control-Request-Private-Network: true".
+		// Optional. Default value false.
+		AllowPrivateNetwork bool `json:"allow_private_network"`
+
 		// ExposeHeaders defines a whitelist headers that clients are allowed to
 		// access.
 		// Optional. Default value []string{}.
@@ -53,7 +85,8 @@ type (
 var (
 	// DefaultCORSConfig is the default CORS middleware config.
 	DefaultCORSConfig = CORSConfig{
-		//Skipper:      defaultSkipper,
+		Skipper:      valse.DefaultSkipper,
+		AllowOrigins: []string{"*"},
 		AllowMethods: []string{strong.GET, strong.HEAD, strong.PUT, strong.PATCH, strong.POST, strong.DELETE},
 	}
 )
@@ -68,41 +101,88 @@ func CORS() valse.MiddlewareHandler {
 // See: `CORS()`.
 func CORSWithConfig(config CORSConfig) valse.MiddlewareHandler {
 	// Defaults
-	/*if config.Skipper == nil {
+	if config.Skipper == nil {
 		config.Skipper = DefaultCORSConfig.Skipper
-	}*/
-
+	}
+	if len(config.AllowOrigins) == 0 && len(config.AllowOriginPatterns) == 0 && config.AllowOriginFunc == nil {
+		config.AllowOrigins = DefaultCORSConfig.AllowOrigins
+	}
 	if len(config.AllowMethods) == 0 {
 		config.AllowMethods = DefaultCORSConfig.AllowMethods
 	}
 
-	allowedOrigins := strings.Join(config.AllowOrigins, ",")
+	var (
+		anyOrigin bool
+		exact     = make(map[string]bool)
+		patterns  []*regexp.Regexp
+	)
+	for _, o := range config.AllowOrigins {
+		switch {
+		case o == "*":
+			anyOrigin = true
+		case strings.Contains(o, "*"):
+			patterns = append(patterns, wildcardPattern(o))
+		default:
+			exact[strings.ToLower(o)] = true
+		}
+	}
+	for _, p := range config.AllowOriginPatterns {
+		patterns = append(patterns, regexp.MustCompile(`^(?:`+p+`)$`))
+	}
+	if anyOrigin && config.AllowCredentials {
+		panic("cors middleware: AllowCredentials cannot be used with the \"*\" origin")
+	}
+
 	allowMethods := strings.Join(config.AllowMethods, ",")
 	allowHeaders := strings.Join(config.AllowHeaders, ",")
 	exposeHeaders := strings.Join(config.ExposeHeaders, ",")
 	maxAge := strconv.Itoa(config.MaxAge)
 
+	// allowOrigin returns the Access-Control-Allow-Origin value for origin,
+	// or "" if it is not allowed.
+	allowOrigin := func(origin string) string {
+		if anyOrigin {
+			return "*"
+		}
+		if exact[strings.ToLower(origin)] {
+			return origin
+		}
+		for _, p := range patterns {
+			if p.MatchString(origin) {
+				return origin
+			}
+		}
+		if config.AllowOriginFunc != nil && config.AllowOriginFunc(origin) {
+			return origin
+		}
+		return ""
+	}
+
 	return func(next valse.RequestHandler) valse.RequestHandler {
 		return func(c *valse.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
 
-			//req := c.Request()
-			//res := c.Response()
 			origin := string(c.Request.Header.Peek(strong.HeaderOrigin))
+			preflight := string(c.Method()) == strong.OPTIONS &&
+				len(c.Request.Header.Peek(strong.HeaderAccessControlRequestMethod)) > 0
 
-			if allowedOrigins == "" {
-				if origin != "" {
-					allowedOrigins = origin
-				} else {
-					if !config.AllowCredentials {
-						allowedOrigins = "*"
-					}
-				}
+			// The response depends on the origin, unless every origin gets "*".
+			if !anyOrigin {
+				c.Response.Header.Add(strong.HeaderVary, strong.HeaderOrigin)
 			}
 
 			// Simple request
-			if string(c.Method()) != strong.OPTIONS {
-				c.Response.Header.Add(strong.HeaderVary, strong.HeaderOrigin)
-				c.Response.Header.Set(strong.HeaderAccessControlAllowOrigin, allowedOrigins)
+			if !preflight {
+				if origin == "" {
+					return next(c)
+				}
+				allowed := allowOrigin(origin)
+				if allowed == "" {
+					return next(c)
+				}
+				c.Response.Header.Set(strong.HeaderAccessControlAllowOrigin, allowed)
 				if config.AllowCredentials {
 					c.Response.Header.Set(strong.HeaderAccessControlAllowCredentials, "true")
 				}
@@ -113,10 +193,18 @@ func CORSWithConfig(config CORSConfig) valse.MiddlewareHandler {
 			}
 
 			// Preflight request
-			c.Response.Header.Add(strong.HeaderVary, strong.HeaderOrigin)
 			c.Response.Header.Add(strong.HeaderVary, strong.HeaderAccessControlRequestMethod)
 			c.Response.Header.Add(strong.HeaderVary, strong.HeaderAccessControlRequestHeaders)
-			c.Response.Header.Set(strong.HeaderAccessControlAllowOrigin, allowedOrigins)
+			c.SetStatusCode(http.StatusNoContent)
+
+			allowed := ""
+			if origin != "" {
+				allowed = allowOrigin(origin)
+			}
+			if allowed == "" {
+				return nil
+			}
+			c.Response.Header.Set(strong.HeaderAccessControlAllowOrigin, allowed)
 			c.Response.Header.Set(strong.HeaderAccessControlAllowMethods, allowMethods)
 			if config.AllowCredentials {
 				c.Response.Header.Set(strong.HeaderAccessControlAllowCredentials, "true")
@@ -129,6 +217,10 @@ func CORSWithConfig(config CORSConfig) valse.MiddlewareHandler {
 					c.Response.Header.Set(strong.HeaderAccessControlAllowHeaders, string(h))
 				}
 			}
+			if config.AllowPrivateNetwork && string(c.Request.Header.Peek(HeaderAccessControlRequestPrivateNetwork)) == "true" {
+				c.Response.Header.Add(strong.HeaderVary, HeaderAccessControlRequestPrivateNetwork)
+				c.Response.Header.Set(HeaderAccessControlAllowPrivateNetwork, "true")
+			}
 			if config.MaxAge > 0 {
 				c.Response.Header.Set(strong.HeaderAccessControlMaxAge, maxAge)
 			}
@@ -137,3 +229,16 @@ func CORSWithConfig(config CORSConfig) valse.MiddlewareHandler {
 		}
 	}
 }
+
+// wildcardPattern compiles an origin with "*" labels, e.g.
+// "https://*.example.com", into a regular expression. A "*" matches one or
+// more subdomain labels; without a scheme any scheme matches.
+func wildcardPattern(origin string) *regexp.Regexp {
+	scheme := `[a-z][a-z0-9+.-]*://`
+	if i := strings.Index(origin, "://"); i >= 0 {
+		scheme = regexp.QuoteMeta(origin[:i+3])
+		origin = origin[i+3:]
+	}
+	host := strings.Replace(regexp.QuoteMeta(origin), `\*`, `[a-z0-9-]+(\.[a-z0-9-]+)*`, -1)
+	return regexp.MustCompile(`(?i)^` + scheme + host + `$`)
+}
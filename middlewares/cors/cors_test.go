@@ -0,0 +1,94 @@
+package cors
+
+import (
+	"testing"
+
+	"github.com/kildevaeld/strong"
+	"github.com/valyala/fasthttp"
+	"github.com/xwinie/valse"
+)
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    CORSConfig
+		origin    string
+		preflight bool
+		// allowed is the expected Access-Control-Allow-Origin.
+		allowed     string
+		credentials bool
+	}{
+		{name: "any origin", config: DefaultCORSConfig, origin: "https://a.com", allowed: "*"},
+		{name: "no origin", config: DefaultCORSConfig},
+		{name: "exact", config: CORSConfig{AllowOrigins: []string{"https://a.com"}}, origin: "https://a.com", allowed: "https://a.com"},
+		{name: "exact other", config: CORSConfig{AllowOrigins: []string{"https://a.com"}}, origin: "https://b.com"},
+		{name: "wildcard", config: CORSConfig{AllowOrigins: []string{"https://*.a.com"}}, origin: "https://x.y.a.com", allowed: "https://x.y.a.com"},
+		{name: "wildcard apex", config: CORSConfig{AllowOrigins: []string{"https://*.a.com"}}, origin: "https://a.com"},
+		{name: "wildcard suffix", config: CORSConfig{AllowOrigins: []string{"https://*.a.com"}}, origin: "https://x.a.com.evil.com"},
+		{name: "pattern", config: CORSConfig{AllowOriginPatterns: []string{`https://[a-z]+\.a\.com`}}, origin: "https://x.a.com", allowed: "https://x.a.com"},
+		{name: "pattern prefix", config: CORSConfig{AllowOriginPatterns: []string{`https://[a-z]+\.a\.com`}}, origin: "https://x.a.com.evil.com"},
+		{name: "pattern suffix", config: CORSConfig{AllowOriginPatterns: []string{`https://[a-z]+\.a\.com`}}, origin: "https://evil.com/https://x.a.com"},
+		{name: "pattern alternation", config: CORSConfig{AllowOriginPatterns: []string{`https://a\.com|https://b\.com`}}, origin: "https://b.com.evil.com"},
+		{name: "func", config: CORSConfig{AllowOriginFunc: func(o string) bool { return o == "https://f.com" }}, origin: "https://f.com", allowed: "https://f.com"},
+		{
+			name:        "credentials",
+			config:      CORSConfig{AllowOrigins: []string{"https://a.com"}, AllowCredentials: true},
+			origin:      "https://a.com",
+			allowed:     "https://a.com",
+			credentials: true,
+		},
+		{
+			name:        "preflight",
+			config:      CORSConfig{AllowOrigins: []string{"https://a.com"}, AllowCredentials: true},
+			origin:      "https://a.com",
+			preflight:   true,
+			allowed:     "https://a.com",
+			credentials: true,
+		},
+		{name: "preflight other", config: CORSConfig{AllowOrigins: []string{"https://a.com"}}, origin: "https://b.com", preflight: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valse.New()
+			s.Use(CORSWithConfig(tt.config))
+			s.Get("/", func(c *valse.Context) error { return nil })
+			s.Options("/", func(c *valse.Context) error { return nil })
+
+			rc := &fasthttp.RequestCtx{}
+			rc.Request.SetRequestURI("/")
+			if tt.origin != "" {
+				rc.Request.Header.Set(strong.HeaderOrigin, tt.origin)
+			}
+			if tt.preflight {
+				rc.Request.Header.SetMethod(strong.OPTIONS)
+				rc.Request.Header.Set(strong.HeaderAccessControlRequestMethod, "POST")
+			}
+			s.GetHandler()(rc)
+
+			if got := string(rc.Response.Header.Peek(strong.HeaderAccessControlAllowOrigin)); got != tt.allowed {
+				t.Errorf("expected Access-Control-Allow-Origin %q, got %q", tt.allowed, got)
+			}
+			credentials := string(rc.Response.Header.Peek(strong.HeaderAccessControlAllowCredentials)) == "true"
+			if credentials != tt.credentials {
+				t.Errorf("expected credentials %v, got %v", tt.credentials, credentials)
+			}
+		})
+	}
+}
+
+func TestCORSAnyOriginWithCredentials(t *testing.T) {
+	for _, config := range []CORSConfig{
+		{AllowCredentials: true},
+		{AllowOrigins: []string{"https://a.com", "*"}, AllowCredentials: true},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected CORSWithConfig(%v) to panic", config.AllowOrigins)
+				}
+			}()
+			CORSWithConfig(config)
+		}()
+	}
+}
@@ -0,0 +1,137 @@
+package jwt
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/xwinie/valse"
+)
+
+// tokenKey stores the verified token independently of JWTConfig.ContextKey,
+// for the accessors below.
+const tokenKey = "valse.jwt.token"
+
+// standardClaims is implemented by jwt.MapClaims and by *jwt.StandardClaims,
+// including structs embedding it.
+type standardClaims interface {
+	VerifyExpiresAt(cmp int64, req bool) bool
+	VerifyIssuedAt(cmp int64, req bool) bool
+	VerifyNotBefore(cmp int64, req bool) bool
+	VerifyIssuer(cmp string, req bool) bool
+}
+
+// newClaims returns a new, zero value of the type of config.Claims, so
+// requests never share a claims value.
+func (config *JWTConfig) newClaims() jwt.Claims {
+	if _, ok := config.Claims.(jwt.MapClaims); ok {
+		return jwt.MapClaims{}
+	}
+	t := reflect.TypeOf(config.Claims)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return reflect.New(t).Interface().(jwt.Claims)
+}
+
+// validateClaims checks the time based claims with config.Leeway, and the
+// issuer and audience. Claims not implementing the standard verifiers are
+// validated with their Valid method.
+func (config *JWTConfig) validateClaims(claims jwt.Claims) error {
+	sc, ok := claims.(standardClaims)
+	if !ok {
+		return claims.Valid()
+	}
+
+	leeway := int64(config.Leeway / time.Second)
+	unix := time.Now().Unix()
+
+	if !sc.VerifyExpiresAt(unix-leeway, false) {
+		return jwt.NewValidationError("token is expired", jwt.ValidationErrorExpired)
+	}
+	if !sc.VerifyIssuedAt(unix+leeway, false) {
+		return jwt.NewValidationError("token used before issued", jwt.ValidationErrorIssuedAt)
+	}
+	if !sc.VerifyNotBefore(unix+leeway, false) {
+		return jwt.NewValidationError("token is not valid yet", jwt.ValidationErrorNotValidYet)
+	}
+	if config.Issuer != "" && !sc.VerifyIssuer(config.Issuer, true) {
+		return jwt.NewValidationError("token has invalid issuer", jwt.ValidationErrorIssuer)
+	}
+	if config.Audience != "" && !hasAudience(claims, config.Audience) {
+		return jwt.NewValidationError("token has invalid audience", jwt.ValidationErrorAudience)
+	}
+	return nil
+}
+
+// hasAudience reports whether the "aud" claim, a string or a list of strings,
+// contains aud.
+func hasAudience(claims jwt.Claims, aud string) bool {
+	if m, ok := claims.(jwt.MapClaims); ok {
+		switch v := m["aud"].(type) {
+		case string:
+			return v == aud
+		case []interface{}:
+			for _, a := range v {
+				if s, ok := a.(string); ok && s == aud {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if v, ok := claims.(interface {
+		VerifyAudience(cmp string, req bool) bool
+	}); ok {
+		return v.VerifyAudience(aud, true)
+	}
+	return false
+}
+
+// TokenFrom returns the token verified by the jwt middleware.
+func TokenFrom(c *valse.Context) (*jwt.Token, bool) {
+	token, ok := c.UserValue(tokenKey).(*jwt.Token)
+	return token, ok
+}
+
+// ClaimsFrom returns the claims of the token verified by the jwt middleware.
+// They have the type of JWTConfig.Claims, which is jwt.MapClaims by default.
+func ClaimsFrom(c *valse.Context) (jwt.Claims, bool) {
+	token, ok := TokenFrom(c)
+	if !ok {
+		return nil, false
+	}
+	return token.Claims, true
+}
+
+// MapClaimsFrom returns the claims of the verified token, if they are
+// jwt.MapClaims.
+func MapClaimsFrom(c *valse.Context) (jwt.MapClaims, bool) {
+	claims, ok := ClaimsFrom(c)
+	if !ok {
+		return nil, false
+	}
+	m, ok := claims.(jwt.MapClaims)
+	return m, ok
+}
+
+// SubjectFrom returns the "sub" claim of the verified token, or an empty
+// string.
+func SubjectFrom(c *valse.Context) string {
+	claims, ok := ClaimsFrom(c)
+	if !ok {
+		return ""
+	}
+	if m, ok := claims.(jwt.MapClaims); ok {
+		sub, _ := m["sub"].(string)
+		return sub
+	}
+	v := reflect.Indirect(reflect.ValueOf(claims))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	if f := v.FieldByName("Subject"); f.IsValid() && f.Kind() == reflect.String {
+		return f.String()
+	}
+	return ""
+}
@@ -0,0 +1,326 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/kildevaeld/strong"
+	"github.com/xwinie/valse"
+)
+
+// Token types, stored in the "typ" claim of issued tokens and as the prefix
+// of their "jti" claim, "refresh-<id>", so they are known from claims
+// structs embedding jwt.StandardClaims.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+type (
+	// IssuerConfig defines the config for an Issuer.
+	IssuerConfig struct {
+		// Signing key to sign tokens: []byte for HMAC, otherwise a
+		// *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey.
+		// Required.
+		SigningKey interface{}
+
+		// Signing method used to sign tokens.
+		// Optional. Default value jwt.SigningMethodHS256.
+		SigningMethod jwt.SigningMethod
+
+		// KeyID is sent in the "kid" header, so verifiers can pick the key
+		// from a key set.
+		// Optional.
+		KeyID string `json:"key_id"`
+
+		// Issuer is set as the "iss" claim.
+		// Optional.
+		Issuer string `json:"issuer"`
+
+		// Audience is set as the "aud" claim.
+		// Optional.
+		Audience string `json:"audience"`
+
+		// AccessTTL is the lifetime of access tokens.
+		// Optional. Default value 15 minutes.
+		AccessTTL time.Duration `json:"access_ttl"`
+
+		// RefreshTTL is the lifetime of refresh tokens.
+		// Optional. Default value 7 days.
+		RefreshTTL time.Duration `json:"refresh_ttl"`
+
+		// Claims returns the extra claims of the access tokens of subject.
+		// It is called again on every refresh, so changed roles are picked
+		// up.
+		// Optional.
+		Claims func(subject string) (map[string]interface{}, error)
+
+		// RevocationStore denylists used refresh tokens and tokens passed to
+		// Revoke. Pass the same store to JWTConfig.RevocationStore.
+		// Optional. Default value a MemoryRevocationStore.
+		RevocationStore RevocationStore
+	}
+
+	// TokenPair is an access token with the refresh token to renew it.
+	TokenPair struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+
+	// Issuer mints, refreshes and revokes tokens.
+	Issuer struct {
+		config    IssuerConfig
+		verifyKey interface{}
+	}
+)
+
+var (
+	// DefaultIssuerConfig is the default Issuer config.
+	DefaultIssuerConfig = IssuerConfig{
+		SigningMethod: jwt.SigningMethodHS256,
+		AccessTTL:     15 * time.Minute,
+		RefreshTTL:    7 * 24 * time.Hour,
+	}
+
+	// ErrInvalidRefreshToken is returned for malformed, expired, revoked or
+	// non-refresh tokens passed to Issuer.Refresh.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+)
+
+// NewIssuer returns an Issuer with config.
+func NewIssuer(config IssuerConfig) *Issuer {
+	// Defaults
+	if config.SigningKey == nil {
+		panic("jwt issuer requires signing key")
+	}
+	if config.SigningMethod == nil {
+		config.SigningMethod = DefaultIssuerConfig.SigningMethod
+	}
+	if config.AccessTTL == 0 {
+		config.AccessTTL = DefaultIssuerConfig.AccessTTL
+	}
+	if config.RefreshTTL == 0 {
+		config.RefreshTTL = DefaultIssuerConfig.RefreshTTL
+	}
+	if config.RevocationStore == nil {
+		config.RevocationStore = NewMemoryRevocationStore()
+	}
+
+	verifyKey := config.SigningKey
+	if signer, ok := config.SigningKey.(crypto.Signer); ok {
+		verifyKey = signer.Public()
+	}
+
+	return &Issuer{config: config, verifyKey: verifyKey}
+}
+
+// Issue returns a new token pair for subject.
+func (i *Issuer) Issue(subject string) (*TokenPair, error) {
+	now := time.Now()
+
+	claims := jwt.MapClaims{}
+	if i.config.Claims != nil {
+		extra, err := i.config.Claims(subject)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range extra {
+			claims[k] = v
+		}
+	}
+
+	access, err := i.sign(claims, subject, TokenTypeAccess, now, i.config.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := i.sign(jwt.MapClaims{}, subject, TokenTypeRefresh, now, i.config.RefreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    bearer,
+		ExpiresIn:    int64(i.config.AccessTTL / time.Second),
+	}, nil
+}
+
+// Refresh validates refreshToken, revokes it and returns a new token pair for
+// its subject. A refresh token can therefore only be used once.
+func (i *Issuer) Refresh(refreshToken string) (*TokenPair, error) {
+	claims, err := i.parse(refreshToken)
+	if err != nil || tokenType(claims) != TokenTypeRefresh {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	jti, _ := claims["jti"].(string)
+	subject, _ := claims["sub"].(string)
+	if jti == "" || subject == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Revoking and checking at once keeps concurrent requests from using
+	// the same refresh token twice.
+	revoked, err := i.config.RevocationStore.RevokeIfNotRevoked(jti, expiry(claims))
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return i.Issue(subject)
+}
+
+// Revoke denylists a token issued by i until it expires.
+func (i *Issuer) Revoke(token string) error {
+	claims, err := i.parse(token)
+	if err != nil {
+		return err
+	}
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return errors.New("token has no jti")
+	}
+	return i.config.RevocationStore.Revoke(jti, expiry(claims))
+}
+
+// RefreshHandler returns a handler exchanging the refresh token, sent as
+// "refresh_token" in a JSON or form body, for a new TokenPair.
+//
+//	g := valse.NewGroup()
+//	g.Post("/token/refresh", issuer.RefreshHandler())
+func (i *Issuer) RefreshHandler() valse.RequestHandler {
+	return func(c *valse.Context) error {
+		var req struct {
+			RefreshToken string `json:"refresh_token" form:"refresh_token" validate:"required"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return err
+		}
+
+		pair, err := i.Refresh(req.RefreshToken)
+		if err == ErrInvalidRefreshToken {
+			return strong.NewHTTPError(http.StatusUnauthorized, err.Error())
+		} else if err != nil {
+			return err
+		}
+
+		c.SetHeader("Cache-Control", "no-store")
+		return c.JSON(pair)
+	}
+}
+
+func (i *Issuer) sign(claims jwt.MapClaims, subject, typ string, now time.Time, ttl time.Duration) (string, error) {
+	jti, err := newJTI(typ)
+	if err != nil {
+		return "", err
+	}
+
+	claims["sub"] = subject
+	claims["typ"] = typ
+	claims["jti"] = jti
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+	if i.config.Issuer != "" {
+		claims["iss"] = i.config.Issuer
+	}
+	if i.config.Audience != "" {
+		claims["aud"] = i.config.Audience
+	}
+
+	token := jwt.NewWithClaims(i.config.SigningMethod, claims)
+	if i.config.KeyID != "" {
+		token.Header["kid"] = i.config.KeyID
+	}
+	return token.SignedString(i.config.SigningKey)
+}
+
+func (i *Issuer) parse(token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	t, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method.Alg() != i.config.SigningMethod.Alg() {
+			return nil, fmt.Errorf("unexpected jwt signing method=%v", t.Header["alg"])
+		}
+		return i.verifyKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !t.Valid {
+		return nil, ErrInvalidRefreshToken
+	}
+	if i.config.Issuer != "" && !claims.VerifyIssuer(i.config.Issuer, true) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if i.config.Audience != "" && !hasAudience(claims, i.config.Audience) {
+		return nil, ErrInvalidRefreshToken
+	}
+	return claims, nil
+}
+
+// newJTI returns a random token id prefixed with the token type.
+func newJTI(typ string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return typ + "-" + hex.EncodeToString(b), nil
+}
+
+func expiry(claims jwt.MapClaims) time.Time {
+	switch exp := claims["exp"].(type) {
+	case float64:
+		return time.Unix(int64(exp), 0)
+	case int64:
+		return time.Unix(exp, 0)
+	}
+	return time.Now().Add(DefaultIssuerConfig.RefreshTTL)
+}
+
+// tokenID returns the "jti" claim of claims, which are jwt.MapClaims or a
+// struct (pointer) with an Id field, such as jwt.StandardClaims.
+func tokenID(claims jwt.Claims) string {
+	if m, ok := claims.(jwt.MapClaims); ok {
+		jti, _ := m["jti"].(string)
+		return jti
+	}
+	v := reflect.Indirect(reflect.ValueOf(claims))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	if f := v.FieldByName("Id"); f.IsValid() && f.Kind() == reflect.String {
+		return f.String()
+	}
+	return ""
+}
+
+// tokenType returns the type of a token issued by an Issuer: the "typ" claim
+// of MapClaims, otherwise the prefix of the "jti" claim. It returns an empty
+// string for other tokens.
+func tokenType(claims jwt.Claims) string {
+	if m, ok := claims.(jwt.MapClaims); ok {
+		if typ, _ := m["typ"].(string); typ != "" {
+			return typ
+		}
+	}
+	jti := tokenID(claims)
+	for _, typ := range []string{TokenTypeAccess, TokenTypeRefresh} {
+		if strings.HasPrefix(jti, typ+"-") {
+			return typ
+		}
+	}
+	return ""
+}
@@ -0,0 +1,165 @@
+package jwt
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/valyala/fasthttp"
+	"github.com/xwinie/valse"
+)
+
+var testKey = []byte("secret")
+
+type customClaims struct {
+	Role string `json:"role"`
+	jwt.StandardClaims
+}
+
+// authorized reports whether the jwt middleware with config lets token
+// through.
+func authorized(config JWTConfig, token string) bool {
+	called := false
+	s := valse.New()
+	s.Use(JWTWithConfig(config))
+	s.Get("/", func(c *valse.Context) error {
+		called = true
+		return nil
+	})
+
+	rc := &fasthttp.RequestCtx{}
+	rc.Request.SetRequestURI("/")
+	rc.Request.Header.Set("Authorization", bearer+" "+token)
+	s.GetHandler()(rc)
+	return called
+}
+
+func TestIssuerTokens(t *testing.T) {
+	issuer := NewIssuer(IssuerConfig{SigningKey: testKey, Issuer: "valse", Audience: "api"})
+	pair, err := issuer.Issue("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		claims jwt.Claims
+		token  string
+		want   bool
+	}{
+		{name: "access", claims: jwt.MapClaims{}, token: pair.AccessToken, want: true},
+		{name: "refresh", claims: jwt.MapClaims{}, token: pair.RefreshToken, want: false},
+		{name: "access custom claims", claims: &customClaims{}, token: pair.AccessToken, want: true},
+		{name: "refresh custom claims", claims: &customClaims{}, token: pair.RefreshToken, want: false},
+		{name: "refresh standard claims", claims: &jwt.StandardClaims{}, token: pair.RefreshToken, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := JWTConfig{SigningKey: testKey, Claims: tt.claims, Issuer: "valse", Audience: "api"}
+			if got := authorized(config, tt.token); got != tt.want {
+				t.Errorf("expected authorized %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIssuerRefresh(t *testing.T) {
+	issuer := NewIssuer(IssuerConfig{SigningKey: testKey, Audience: "api"})
+	other := NewIssuer(IssuerConfig{SigningKey: testKey, Audience: "admin"})
+
+	tests := []struct {
+		name  string
+		token func(t *testing.T) string
+		ok    bool
+	}{
+		{
+			name: "refresh token",
+			token: func(t *testing.T) string {
+				return issue(t, issuer).RefreshToken
+			},
+			ok: true,
+		},
+		{
+			name: "access token",
+			token: func(t *testing.T) string {
+				return issue(t, issuer).AccessToken
+			},
+		},
+		{
+			name: "used refresh token",
+			token: func(t *testing.T) string {
+				token := issue(t, issuer).RefreshToken
+				if _, err := issuer.Refresh(token); err != nil {
+					t.Fatal(err)
+				}
+				return token
+			},
+		},
+		{
+			name: "revoked refresh token",
+			token: func(t *testing.T) string {
+				token := issue(t, issuer).RefreshToken
+				if err := issuer.Revoke(token); err != nil {
+					t.Fatal(err)
+				}
+				return token
+			},
+		},
+		{
+			name: "other audience",
+			token: func(t *testing.T) string {
+				return issue(t, other).RefreshToken
+			},
+		},
+		{
+			name: "malformed",
+			token: func(t *testing.T) string {
+				return "abc"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pair, err := issuer.Refresh(tt.token(t))
+			if tt.ok && (err != nil || pair == nil) {
+				t.Errorf("Refresh() = %v, %v, expected a token pair", pair, err)
+			}
+			if !tt.ok && err != ErrInvalidRefreshToken {
+				t.Errorf("Refresh() = %v, %v, expected ErrInvalidRefreshToken", pair, err)
+			}
+		})
+	}
+}
+
+func TestIssuerRefreshOnce(t *testing.T) {
+	issuer := NewIssuer(IssuerConfig{SigningKey: testKey})
+	token := issue(t, issuer).RefreshToken
+
+	var refreshed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := issuer.Refresh(token); err == nil {
+				atomic.AddInt32(&refreshed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if refreshed != 1 {
+		t.Errorf("expected the refresh token to be used once, used %d times", refreshed)
+	}
+}
+
+func issue(t *testing.T, issuer *Issuer) *TokenPair {
+	pair, err := issuer.Issue("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair
+}
@@ -4,12 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"reflect"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/kildevaeld/strong"
-	"github.com/kildevaeld/valse"
+	"github.com/xwinie/valse"
 )
 
 // Shamefully stolen from the echo framework https://github.com/labstack/echo
@@ -24,24 +24,50 @@ type (
 	// JWTConfig defines the config for JWT middleware.
 	JWTConfig struct {
 		// Skipper defines a function to skip middleware.
-		//Skipper middleware.Skipper
+		// Optional. Default value valse.DefaultSkipper.
+		Skipper valse.Skipper
 
 		// Signing key to validate token.
-		// Required.
+		// Required, unless KeySet is set.
 		SigningKey interface{} `json:"signing_key"`
 
 		// Signing method, used to check token signing method.
 		// Optional. Default value HS256.
 		SigningMethod string `json:"signing_method"`
 
+		// KeySet selects the key by the "kid" header of the token, e.g. a
+		// JWKS loaded with NewJWKS. It takes precedence over SigningKey.
+		// Optional.
+		KeySet KeySet
+
+		// SigningMethods restricts the algorithms accepted with keys from
+		// KeySet. If empty, every algorithm matching the key type is
+		// accepted, see Key.Allows.
+		// Optional.
+		SigningMethods []string `json:"signing_methods"`
+
 		// Context key to store user information from the token into context.
 		// Optional. Default value "user".
 		ContextKey string `json:"context_key"`
 
-		// Claims are extendable claims data defining token content.
+		// Claims are extendable claims data defining token content. Only its
+		// type is used: every request decodes into a new value of it.
 		// Optional. Default value jwt.MapClaims
 		Claims jwt.Claims
 
+		// Issuer, if set, must equal the "iss" claim.
+		// Optional.
+		Issuer string `json:"issuer"`
+
+		// Audience, if set, must be in the "aud" claim.
+		// Optional.
+		Audience string `json:"audience"`
+
+		// Leeway is the clock skew tolerated when checking the "exp", "nbf"
+		// and "iat" claims.
+		// Optional. Default value 0.
+		Leeway time.Duration `json:"leeway"`
+
 		// TokenLookup is a string in the form of "<source>:<name>" that is used
 		// to extract token from the request.
 		// Optional. Default value "header:Authorization".
@@ -51,6 +77,11 @@ type (
 		// - "cookie:<name>"
 		TokenLookup *TokenLookup `json:"token_lookup"`
 
+		// RevocationStore rejects tokens whose "jti" claim is revoked, see
+		// Issuer.
+		// Optional.
+		RevocationStore RevocationStore
+
 		keyFunc jwt.Keyfunc
 	}
 
@@ -64,14 +95,22 @@ const (
 // Algorithims
 const (
 	AlgorithmHS256 = "HS256"
+	AlgorithmHS384 = "HS384"
+	AlgorithmHS512 = "HS512"
+	AlgorithmRS256 = "RS256"
+	AlgorithmRS384 = "RS384"
+	AlgorithmRS512 = "RS512"
+	AlgorithmPS256 = "PS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmES384 = "ES384"
+	AlgorithmES512 = "ES512"
+	AlgorithmEdDSA = "EdDSA"
 )
 
 var (
 	// DefaultJWTConfig is the default JWT auth middleware config.
 	DefaultJWTConfig = JWTConfig{
-		/*Skipper: func(c *fasthttp.RequestCtx) bool {
-			return false
-		},*/
+		Skipper:       valse.DefaultSkipper,
 		SigningMethod: AlgorithmHS256,
 		ContextKey:    "user",
 		TokenLookup: &TokenLookup{
@@ -99,11 +138,11 @@ func JWT(key []byte) valse.MiddlewareHandler {
 // See: `JWT()`.
 func JWTWithConfig(config JWTConfig) valse.MiddlewareHandler {
 	// Defaults
-	/*if config.Skipper == nil {
+	if config.Skipper == nil {
 		config.Skipper = DefaultJWTConfig.Skipper
-	}*/
-	if config.SigningKey == nil {
-		panic("jwt middleware requires signing key")
+	}
+	if config.SigningKey == nil && config.KeySet == nil {
+		panic("jwt middleware requires signing key or key set")
 	}
 	if config.SigningMethod == "" {
 		config.SigningMethod = DefaultJWTConfig.SigningMethod
@@ -118,6 +157,9 @@ func JWTWithConfig(config JWTConfig) valse.MiddlewareHandler {
 		config.TokenLookup = DefaultJWTConfig.TokenLookup
 	}
 	config.keyFunc = func(t *jwt.Token) (interface{}, error) {
+		if config.KeySet != nil {
+			return config.keyFromSet(t)
+		}
 		// Check the signing method
 		if t.Method.Alg() != config.SigningMethod {
 			return nil, fmt.Errorf("unexpected jwt signing method=%v", t.Header["alg"])
@@ -127,6 +169,9 @@ func JWTWithConfig(config JWTConfig) valse.MiddlewareHandler {
 
 	// Initialize
 
+	// Claims are validated by validateClaims, which applies the leeway.
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+
 	var extractors jwtExtractors
 
 	if config.TokenLookup.Header != "" {
@@ -141,25 +186,37 @@ func JWTWithConfig(config JWTConfig) valse.MiddlewareHandler {
 
 	return func(next valse.RequestHandler) valse.RequestHandler {
 		return func(c *valse.Context) error {
-			/*if config.Skipper(c) {
+			if config.Skipper(c) {
 				return next(c)
-			}*/
+			}
 
 			auth, err := extractors.fromContext(c)
 			if err != nil {
 				return strong.NewHTTPError(http.StatusBadRequest, "Invalid Auhtorization header")
 			}
-			token := new(jwt.Token)
-			// Issue #647, #656
-			if _, ok := config.Claims.(jwt.MapClaims); ok {
-				token, err = jwt.Parse(auth, config.keyFunc)
-			} else {
-				claims := reflect.ValueOf(config.Claims).Interface().(jwt.Claims)
-				token, err = jwt.ParseWithClaims(auth, claims, config.keyFunc)
-			}
+			token, err := parser.ParseWithClaims(auth, config.newClaims(), config.keyFunc)
 			if err == nil && token.Valid {
+				if err := config.validateClaims(token.Claims); err != nil {
+					return strong.ErrUnauthorized
+				}
+				// Refresh tokens are only accepted by Issuer.Refresh.
+				if tokenType(token.Claims) == TokenTypeRefresh {
+					return strong.ErrUnauthorized
+				}
+				if config.RevocationStore != nil {
+					if jti := tokenID(token.Claims); jti != "" {
+						revoked, err := config.RevocationStore.IsRevoked(jti)
+						if err != nil {
+							return err
+						}
+						if revoked {
+							return strong.ErrUnauthorized
+						}
+					}
+				}
 				// Store user information from token into context.
 				c.SetUserValue(config.ContextKey, token)
+				c.SetUserValue(tokenKey, token)
 				return next(c)
 			}
 
@@ -168,6 +225,34 @@ func JWTWithConfig(config JWTConfig) valse.MiddlewareHandler {
 	}
 }
 
+// keyFromSet returns the key named by the token's kid header, after checking
+// that the key and SigningMethods allow the token's algorithm.
+func (config *JWTConfig) keyFromSet(t *jwt.Token) (interface{}, error) {
+	alg := t.Method.Alg()
+	if len(config.SigningMethods) > 0 {
+		allowed := false
+		for _, m := range config.SigningMethods {
+			if m == alg {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, fmt.Errorf("unexpected jwt signing method=%v", t.Header["alg"])
+		}
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	key, err := config.KeySet.Key(kid)
+	if err != nil {
+		return nil, err
+	}
+	if !key.Allows(alg) {
+		return nil, fmt.Errorf("jwt signing method=%v not allowed for key '%s'", t.Header["alg"], kid)
+	}
+	return key.Key, nil
+}
+
 type jwtExtractors []jwtExtractor
 
 func (jwt *jwtExtractors) fromContext(ctx *valse.Context) (string, error) {
@@ -0,0 +1,204 @@
+package jwt
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/valyala/fasthttp"
+	"github.com/xwinie/valse"
+)
+
+func sign(t *testing.T, claims jwt.Claims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestJWTSkipper(t *testing.T) {
+	s := valse.New()
+	s.Use(JWTWithConfig(JWTConfig{
+		SigningKey: testKey,
+		Skipper: func(c *valse.Context) bool {
+			return strings.HasPrefix(string(c.Path()), "/public")
+		},
+	}))
+	handler := func(c *valse.Context) error {
+		return c.Text("ok")
+	}
+	s.Get("/public/a", handler)
+	s.Get("/private", handler)
+
+	tests := []struct {
+		path   string
+		token  string
+		status int
+	}{
+		{path: "/public/a", status: 200},
+		{path: "/private", status: 400},
+		{path: "/private", token: "invalid", status: 401},
+		{path: "/private", token: sign(t, jwt.MapClaims{"sub": "alice"}), status: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rc := &fasthttp.RequestCtx{}
+			rc.Request.SetRequestURI(tt.path)
+			if tt.token != "" {
+				rc.Request.Header.Set("Authorization", bearer+" "+tt.token)
+			}
+			s.GetHandler()(rc)
+			if got := rc.Response.StatusCode(); got != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, got)
+			}
+		})
+	}
+}
+
+func TestJWTLeeway(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-5 * time.Second).Unix()
+	future := now.Add(5 * time.Second).Unix()
+
+	tests := []struct {
+		name   string
+		claims jwt.Claims
+		leeway time.Duration
+		want   bool
+	}{
+		{name: "expired", claims: jwt.MapClaims{"exp": past}},
+		{name: "expired within leeway", claims: jwt.MapClaims{"exp": past}, leeway: 10 * time.Second, want: true},
+		{name: "expired beyond leeway", claims: jwt.MapClaims{"exp": past}, leeway: 2 * time.Second},
+		{name: "not yet valid", claims: jwt.MapClaims{"nbf": future}},
+		{name: "not yet valid within leeway", claims: jwt.MapClaims{"nbf": future}, leeway: 10 * time.Second, want: true},
+		{name: "not yet valid beyond leeway", claims: jwt.MapClaims{"nbf": future}, leeway: 2 * time.Second},
+		{name: "standard claims expired", claims: &jwt.StandardClaims{ExpiresAt: past}},
+		{name: "standard claims expired within leeway", claims: &jwt.StandardClaims{ExpiresAt: past}, leeway: 10 * time.Second, want: true},
+		{name: "standard claims not yet valid within leeway", claims: &jwt.StandardClaims{NotBefore: future}, leeway: 10 * time.Second, want: true},
+		{name: "valid", claims: jwt.MapClaims{"exp": future, "nbf": past}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := JWTConfig{SigningKey: testKey, Leeway: tt.leeway}
+			if _, ok := tt.claims.(*jwt.StandardClaims); ok {
+				config.Claims = &jwt.StandardClaims{}
+			}
+			if got := authorized(config, sign(t, tt.claims)); got != tt.want {
+				t.Errorf("expected authorized %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClaimsFrom(t *testing.T) {
+	tests := []struct {
+		name   string
+		config jwt.Claims
+		token  jwt.Claims
+		// kind is the Go type of the claims returned by ClaimsFrom.
+		kind    string
+		subject string
+		isMap   bool
+	}{
+		{name: "map claims", token: jwt.MapClaims{"sub": "alice"}, kind: "jwt.MapClaims", subject: "alice", isMap: true},
+		{name: "standard claims", config: &jwt.StandardClaims{}, token: &jwt.StandardClaims{Subject: "bob"}, kind: "*jwt.StandardClaims", subject: "bob"},
+		{name: "custom claims", config: &customClaims{}, token: &customClaims{Role: "admin", StandardClaims: jwt.StandardClaims{Subject: "carol"}}, kind: "*jwt.customClaims", subject: "carol"},
+		{name: "no subject", token: jwt.MapClaims{"name": "dave"}, kind: "jwt.MapClaims", isMap: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valse.New()
+			s.Use(JWTWithConfig(JWTConfig{SigningKey: testKey, Claims: tt.config}))
+			var (
+				claims  jwt.Claims
+				ok      bool
+				subject string
+				isMap   bool
+			)
+			s.Get("/", func(c *valse.Context) error {
+				claims, ok = ClaimsFrom(c)
+				subject = SubjectFrom(c)
+				_, isMap = MapClaimsFrom(c)
+				return nil
+			})
+
+			rc := &fasthttp.RequestCtx{}
+			rc.Request.SetRequestURI("/")
+			rc.Request.Header.Set("Authorization", bearer+" "+sign(t, tt.token))
+			s.GetHandler()(rc)
+
+			if !ok {
+				t.Fatal("expected claims")
+			}
+			if kind := fmt.Sprintf("%T", claims); kind != tt.kind {
+				t.Errorf("expected claims of type %s, got %s", tt.kind, kind)
+			}
+			if subject != tt.subject {
+				t.Errorf("expected subject %q, got %q", tt.subject, subject)
+			}
+			if isMap != tt.isMap {
+				t.Errorf("expected MapClaimsFrom ok %v, got %v", tt.isMap, isMap)
+			}
+		})
+	}
+
+	t.Run("no token", func(t *testing.T) {
+		c := &valse.Context{RequestCtx: &fasthttp.RequestCtx{}}
+		if claims, ok := ClaimsFrom(c); ok || claims != nil {
+			t.Errorf("expected no claims, got %v", claims)
+		}
+		if _, ok := MapClaimsFrom(c); ok {
+			t.Error("expected no map claims")
+		}
+		if sub := SubjectFrom(c); sub != "" {
+			t.Errorf("expected no subject, got %q", sub)
+		}
+	})
+}
+
+// TestJWTConcurrentClaims checks that concurrent requests decode into claims
+// of their own. Run it with -race.
+func TestJWTConcurrentClaims(t *testing.T) {
+	s := valse.New()
+	s.Use(JWTWithConfig(JWTConfig{SigningKey: testKey, Claims: &customClaims{}}))
+	s.Get("/", func(c *valse.Context) error {
+		claims, _ := ClaimsFrom(c)
+		// Give the other requests time to decode their tokens.
+		time.Sleep(time.Millisecond)
+		return c.Text(SubjectFrom(c) + "," + claims.(*customClaims).Role)
+	})
+	h := s.GetHandler()
+
+	const n = 50
+	tokens := make([]string, n)
+	for i := range tokens {
+		tokens[i] = sign(t, &customClaims{
+			Role:           fmt.Sprintf("role-%d", i),
+			StandardClaims: jwt.StandardClaims{Subject: fmt.Sprintf("user-%d", i)},
+		})
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rc := &fasthttp.RequestCtx{}
+			rc.Request.SetRequestURI("/")
+			rc.Request.Header.Set("Authorization", bearer+" "+tokens[i])
+			h(rc)
+			want := fmt.Sprintf("user-%d,role-%d", i, i)
+			if got := string(rc.Response.Body()); got != want {
+				t.Errorf("expected claims %q, got %q", want, got)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,400 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Key is a verification key with its key id.
+type Key struct {
+	// ID is matched against the "kid" header of the token.
+	ID string
+	// Algorithm restricts the key to a single alg. If empty every alg of
+	// the key's family is accepted, e.g. RS256, RS384, PS256... for RSA keys.
+	Algorithm string
+	// Key is a *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey or, for
+	// HMAC, []byte.
+	Key interface{}
+}
+
+// Allows reports whether the key may verify tokens signed with alg.
+func (k *Key) Allows(alg string) bool {
+	if k.Algorithm != "" {
+		return k.Algorithm == alg
+	}
+	switch key := k.Key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return alg == "ES256"
+		case elliptic.P384():
+			return alg == "ES384"
+		case elliptic.P521():
+			return alg == "ES512"
+		}
+	case ed25519.PublicKey:
+		return alg == AlgorithmEdDSA
+	case []byte:
+		return strings.HasPrefix(alg, "HS")
+	}
+	return false
+}
+
+// KeySet looks up verification keys by key id.
+type KeySet interface {
+	// Key returns the key with the given id. An empty kid is passed for
+	// tokens without a "kid" header.
+	Key(kid string) (*Key, error)
+}
+
+// ErrKeyNotFound is returned by key sets that do not know a key id.
+var ErrKeyNotFound = errors.New("jwt key not found")
+
+// StaticKeySet is a fixed KeySet.
+type StaticKeySet map[string]*Key
+
+// NewStaticKeySet returns a key set holding keys.
+func NewStaticKeySet(keys ...*Key) StaticKeySet {
+	s := make(StaticKeySet, len(keys))
+	for _, k := range keys {
+		s[k.ID] = k
+	}
+	return s
+}
+
+// Key implements KeySet.
+func (s StaticKeySet) Key(kid string) (*Key, error) {
+	if k, ok := s[kid]; ok {
+		return k, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+// JWKSConfig defines where a JWKS key set is loaded from and how often it
+// is refreshed.
+type JWKSConfig struct {
+	// URL of the JWKS document. Either URL or File is required.
+	URL string `json:"url"`
+
+	// File holding the JWKS document.
+	File string `json:"file"`
+
+	// RefreshInterval is how long a loaded document is used before it is
+	// loaded again.
+	// Optional. Default value 1 hour.
+	RefreshInterval time.Duration `json:"refresh_interval"`
+
+	// RefreshUnknownInterval is the minimum time between loads triggered by
+	// tokens with an unknown key id, which happen when keys are rotated.
+	// Optional. Default value 1 minute.
+	RefreshUnknownInterval time.Duration `json:"refresh_unknown_interval"`
+
+	// Client is used to fetch URL.
+	// Optional. Default value a client with a 10 second timeout.
+	Client *http.Client
+}
+
+var (
+	// DefaultJWKSConfig is the default JWKS key set config.
+	DefaultJWKSConfig = JWKSConfig{
+		RefreshInterval:        time.Hour,
+		RefreshUnknownInterval: time.Minute,
+	}
+)
+
+// JWKS is a KeySet loaded from a JSON Web Key Set document, see RFC 7517.
+// The document is loaded again when it is older than the refresh interval or
+// when a token refers to an unknown key id. If loading fails the previous
+// keys are kept.
+type JWKS struct {
+	config JWKSConfig
+
+	mu     sync.Mutex
+	keys   map[string]*Key
+	loaded time.Time
+	// loading is the load in progress, waited for by concurrent refreshes.
+	loading *jwksLoad
+}
+
+type jwksLoad struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWKS returns a JWKS key set and loads it for the first time.
+func NewJWKS(config JWKSConfig) (*JWKS, error) {
+	if config.URL == "" && config.File == "" {
+		return nil, errors.New("jwks requires an url or a file")
+	}
+	if config.RefreshInterval == 0 {
+		config.RefreshInterval = DefaultJWKSConfig.RefreshInterval
+	}
+	if config.RefreshUnknownInterval == 0 {
+		config.RefreshUnknownInterval = DefaultJWKSConfig.RefreshUnknownInterval
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	j := &JWKS{config: config}
+	if err := j.Refresh(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// NewJWKSFromURL returns a JWKS key set fetched from url.
+func NewJWKSFromURL(url string) (*JWKS, error) {
+	c := DefaultJWKSConfig
+	c.URL = url
+	return NewJWKS(c)
+}
+
+// NewJWKSFromFile returns a JWKS key set read from file.
+func NewJWKSFromFile(file string) (*JWKS, error) {
+	c := DefaultJWKSConfig
+	c.File = file
+	return NewJWKS(c)
+}
+
+// Key implements KeySet. An unknown key id loads the document again at most
+// once per refresh unknown interval.
+func (j *JWKS) Key(kid string) (*Key, error) {
+	j.refresh(j.config.RefreshInterval)
+
+	k, ok := j.key(kid)
+	if !ok && j.refresh(j.config.RefreshUnknownInterval) {
+		k, ok = j.key(kid)
+	}
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return k, nil
+}
+
+func (j *JWKS) key(kid string) (*Key, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	k, ok := j.keys[kid]
+	return k, ok
+}
+
+// Refresh loads the document.
+func (j *JWKS) Refresh() error {
+	j.mu.Lock()
+	l := j.start(-1)
+	j.mu.Unlock()
+	return j.wait(l)
+}
+
+// refresh loads the document if it is older than maxAge, or waits for the
+// load in progress. It reports whether the keys may have changed.
+func (j *JWKS) refresh(maxAge time.Duration) bool {
+	j.mu.Lock()
+	l := j.start(maxAge)
+	j.mu.Unlock()
+	if l == nil {
+		return false
+	}
+	j.wait(l)
+	return true
+}
+
+// start returns the load in progress or, if the document is older than
+// maxAge, starts a new one. It returns nil if the document is recent enough.
+// j.mu must be held.
+func (j *JWKS) start(maxAge time.Duration) *jwksLoad {
+	if j.loading != nil {
+		return j.loading
+	}
+	if maxAge >= 0 && time.Since(j.loaded) <= maxAge {
+		return nil
+	}
+
+	// loaded is also set on failure, so an unreachable endpoint is not hit
+	// on every request.
+	j.loaded = time.Now()
+	l := &jwksLoad{done: make(chan struct{})}
+	j.loading = l
+	go func() {
+		keys, err := j.parse()
+		j.mu.Lock()
+		if err == nil {
+			j.keys = keys
+		}
+		j.loading = nil
+		j.mu.Unlock()
+		l.err = err
+		close(l.done)
+	}()
+	return l
+}
+
+func (j *JWKS) wait(l *jwksLoad) error {
+	<-l.done
+	return l.err
+}
+
+func (j *JWKS) parse() (map[string]*Key, error) {
+	data, err := j.load()
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+func (j *JWKS) load() ([]byte, error) {
+	if j.config.File != "" {
+		return ioutil.ReadFile(j.config.File)
+	}
+
+	res, err := j.config.Client.Get(j.config.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: unexpected status %d from %s", res.StatusCode, j.config.URL)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS parses a JSON Web Key Set document into keys by key id. RSA, EC
+// (P-256, P-384, P-521) and OKP (Ed25519) public keys are supported; keys of
+// other types or curves and keys not meant for signatures are skipped. oct
+// keys are skipped too, a published HMAC secret lets anyone sign tokens; use
+// a StaticKeySet for HMAC keys.
+func ParseJWKS(data []byte) (map[string]*Key, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*Key, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks: key '%s': %v", jwk.Kid, err)
+		}
+		if key == nil {
+			continue
+		}
+		keys[jwk.Kid] = &Key{ID: jwk.Kid, Algorithm: jwk.Alg, Key: key}
+	}
+	return keys, nil
+}
+
+func (jwk *jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, nil
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	bs, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(bs), nil
+}
+
+// ParsePublicKeyFromPEM parses a PEM encoded PKIX public key or certificate
+// into a *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey.
+func ParsePublicKeyFromPEM(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("key must be PEM encoded")
+	}
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		return cert.PublicKey, nil
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// ParsePublicKeyFromPEMFile reads a PEM file, see ParsePublicKeyFromPEM.
+func ParsePublicKeyFromPEMFile(file string) (interface{}, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePublicKeyFromPEM(data)
+}
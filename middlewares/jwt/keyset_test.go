@@ -0,0 +1,206 @@
+package jwt
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksServer serves a JWKS document with an Ed25519 key per kid and counts
+// the requests.
+type jwksServer struct {
+	*httptest.Server
+	hits int32
+
+	mu     sync.Mutex
+	kids   []string
+	status int
+	block  chan struct{}
+}
+
+// ed25519X is the public key of RFC 8037, appendix A.2.
+const ed25519X = "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"
+
+func newJWKSServer(kids ...string) *jwksServer {
+	s := &jwksServer{kids: kids, status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.hits, 1)
+		s.mu.Lock()
+		kids, status, block := s.kids, s.status, s.block
+		s.mu.Unlock()
+		if block != nil {
+			<-block
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, `{"keys":[`)
+		for i, kid := range kids {
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"kty":"OKP","crv":"Ed25519","kid":%q,"x":%q}`, kid, ed25519X)
+		}
+		fmt.Fprint(w, `]}`)
+	}))
+	return s
+}
+
+func (s *jwksServer) set(status int, kids ...string) {
+	s.mu.Lock()
+	s.status, s.kids = status, kids
+	s.mu.Unlock()
+}
+
+func (s *jwksServer) requests() int {
+	return int(atomic.LoadInt32(&s.hits))
+}
+
+func TestJWKS(t *testing.T) {
+	const interval = 50 * time.Millisecond
+
+	tests := []struct {
+		name string
+		// refresh and unknown are the refresh intervals, in intervals.
+		refresh, unknown time.Duration
+		// serve is the status and kids served after the first load.
+		status int
+		kids   []string
+		// wait is how long to wait before looking up kid.
+		wait     time.Duration
+		kid      string
+		found    bool
+		requests int
+	}{
+		{name: "loaded", refresh: 10, unknown: 10, kid: "k1", found: true, requests: 1},
+		{name: "unknown kid rate limited", refresh: 10, unknown: 10, kids: []string{"k2"}, kid: "k2", requests: 1},
+		{name: "rotated", refresh: 10, unknown: 1, kids: []string{"k2"}, wait: 2, kid: "k2", found: true, requests: 2},
+		{name: "rotated out", refresh: 1, unknown: 10, kids: []string{"k2"}, wait: 2, kid: "k1", requests: 2},
+		{name: "expired with unknown kid", refresh: 1, unknown: 1, kids: []string{"k2"}, wait: 2, kid: "k3", requests: 2},
+		{name: "failed load keeps keys", refresh: 1, unknown: 1, status: http.StatusInternalServerError, wait: 2, kid: "k1", found: true, requests: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newJWKSServer("k1")
+			defer srv.Close()
+
+			j, err := NewJWKS(JWKSConfig{
+				URL:                    srv.URL,
+				RefreshInterval:        tt.refresh * interval,
+				RefreshUnknownInterval: tt.unknown * interval,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			status := tt.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			srv.set(status, tt.kids...)
+			time.Sleep(tt.wait * interval)
+
+			k, err := j.Key(tt.kid)
+			if tt.found && (err != nil || k.ID != tt.kid) {
+				t.Errorf("Key(%q) = %v, %v, expected the key", tt.kid, k, err)
+			}
+			if !tt.found && err != ErrKeyNotFound {
+				t.Errorf("Key(%q) = %v, %v, expected ErrKeyNotFound", tt.kid, k, err)
+			}
+			if got := srv.requests(); got != tt.requests {
+				t.Errorf("expected %d requests, got %d", tt.requests, got)
+			}
+		})
+	}
+}
+
+func TestJWKSConcurrentRefresh(t *testing.T) {
+	srv := newJWKSServer("k1")
+	defer srv.Close()
+
+	j, err := NewJWKS(JWKSConfig{URL: srv.URL, RefreshInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := make(chan struct{})
+	srv.mu.Lock()
+	srv.block = block
+	srv.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := j.Key("k1"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(block)
+	wg.Wait()
+
+	if got := srv.requests(); got != 2 {
+		t.Errorf("expected the concurrent lookups to share 1 load, got %d loads", got-1)
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		// kind is the Go type of the parsed key, empty if it is skipped.
+		kind string
+		err  bool
+	}{
+		{name: "rsa", key: `{"kty":"RSA","n":"AQAB","e":"AQAB"}`, kind: "*rsa.PublicKey"},
+		{name: "ec", key: `{"kty":"EC","crv":"P-256","x":"axfR8uEsQkf4vOblY6RA8ncDfYEt6zOg9KE5RdiYwpY","y":"T-NC4v4af5uO5-tKfA-eFivOM1drMV7Oy7ZAaDe_UfU"}`, kind: "*ecdsa.PublicKey"},
+		{name: "ed25519", key: `{"kty":"OKP","crv":"Ed25519","x":"` + ed25519X + `"}`, kind: "ed25519.PublicKey"},
+		{name: "oct", key: `{"kty":"oct","k":"c2VjcmV0"}`},
+		{name: "oct for signatures", key: `{"kty":"oct","use":"sig","alg":"HS256","k":"c2VjcmV0"}`},
+		{name: "unsupported curve", key: `{"kty":"EC","crv":"P-192","x":"AA","y":"AA"}`},
+		{name: "unsupported okp curve", key: `{"kty":"OKP","crv":"X25519","x":"AA"}`},
+		{name: "unknown type", key: `{"kty":"XYZ"}`},
+		{name: "encryption", key: `{"kty":"OKP","crv":"Ed25519","use":"enc","x":"` + ed25519X + `"}`},
+		{name: "point not on curve", key: `{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}`, err: true},
+		{name: "invalid ed25519 size", key: `{"kty":"OKP","crv":"Ed25519","x":"AQ"}`, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The valid key next to the tested one must always be parsed.
+			doc := `{"keys":[` + tt.key[:1] + `"kid":"tested",` + tt.key[1:] +
+				`,{"kty":"OKP","crv":"Ed25519","kid":"valid","x":"` + ed25519X + `"}]}`
+			keys, err := ParseJWKS([]byte(doc))
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected an error, got %v", keys)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if keys["valid"] == nil {
+				t.Error("expected the valid key to be parsed")
+			}
+			key, ok := keys["tested"]
+			if tt.kind == "" {
+				if ok {
+					t.Errorf("expected the key to be skipped, got %T", key.Key)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("expected a %s, the key was skipped", tt.kind)
+			}
+			if kind := fmt.Sprintf("%T", key.Key); kind != tt.kind {
+				t.Errorf("expected a %s, got %s", tt.kind, kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package jwt
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RevocationStore keeps the ids ("jti" claim) of revoked tokens. The jwt
+// middleware rejects tokens whose id is revoked.
+type RevocationStore interface {
+	// Revoke denylists jti until the given time, usually the expiry of the
+	// token after which it is rejected anyway.
+	Revoke(jti string, until time.Time) error
+	// IsRevoked reports whether jti is denylisted.
+	IsRevoked(jti string) (bool, error)
+	// RevokeIfNotRevoked atomically revokes jti unless it is already
+	// denylisted. It reports whether jti was revoked by this call.
+	RevokeIfNotRevoked(jti string, until time.Time) (bool, error)
+}
+
+// MemoryRevocationStore is a RevocationStore kept in memory. Revocations are
+// lost on restart and not shared between instances.
+type MemoryRevocationStore struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+}
+
+// NewMemoryRevocationStore returns an empty in-memory revocation store.
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{revoked: make(map[string]time.Time)}
+}
+
+// Revoke implements RevocationStore.
+func (m *MemoryRevocationStore) Revoke(jti string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(time.Now())
+	m.revoked[jti] = until
+	return nil
+}
+
+// IsRevoked implements RevocationStore.
+func (m *MemoryRevocationStore) IsRevoked(jti string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.isRevoked(jti, time.Now()), nil
+}
+
+// RevokeIfNotRevoked implements RevocationStore.
+func (m *MemoryRevocationStore) RevokeIfNotRevoked(jti string, until time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if m.isRevoked(jti, now) {
+		return false, nil
+	}
+	m.prune(now)
+	m.revoked[jti] = until
+	return true, nil
+}
+
+func (m *MemoryRevocationStore) isRevoked(jti string, now time.Time) bool {
+	until, ok := m.revoked[jti]
+	return ok && now.Before(until)
+}
+
+// prune drops expired revocations. The caller must hold the write lock.
+func (m *MemoryRevocationStore) prune(now time.Time) {
+	for jti, until := range m.revoked {
+		if !now.Before(until) {
+			delete(m.revoked, jti)
+		}
+	}
+}
+
+// FileRevocationStore is a RevocationStore kept in memory and appended to a
+// file, one "<jti> <unix expiry>" line per revocation, so revocations
+// survive restarts. Expired lines are dropped when the file is opened.
+type FileRevocationStore struct {
+	mem  *MemoryRevocationStore
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileRevocationStore opens, or creates, the revocation file at path.
+func NewFileRevocationStore(path string) (*FileRevocationStore, error) {
+	mem := NewMemoryRevocationStore()
+
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) != 2 {
+				continue
+			}
+			exp, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			mem.revoked[fields[0]] = time.Unix(exp, 0)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	mem.prune(time.Now())
+
+	// Compact the file to the revocations still in effect.
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	for jti, until := range mem.revoked {
+		fmt.Fprintf(w, "%s %d\n", jti, until.Unix())
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileRevocationStore{mem: mem, file: file}, nil
+}
+
+// Revoke implements RevocationStore.
+func (s *FileRevocationStore) Revoke(jti string, until time.Time) error {
+	if strings.ContainsAny(jti, " \t\r\n") {
+		return fmt.Errorf("invalid jti '%s'", jti)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := fmt.Fprintf(s.file, "%s %d\n", jti, until.Unix()); err != nil {
+		return err
+	}
+	return s.mem.Revoke(jti, until)
+}
+
+// IsRevoked implements RevocationStore.
+func (s *FileRevocationStore) IsRevoked(jti string) (bool, error) {
+	return s.mem.IsRevoked(jti)
+}
+
+// RevokeIfNotRevoked implements RevocationStore.
+func (s *FileRevocationStore) RevokeIfNotRevoked(jti string, until time.Time) (bool, error) {
+	if strings.ContainsAny(jti, " \t\r\n") {
+		return false, fmt.Errorf("invalid jti '%s'", jti)
+	}
+
+	// Revocations are only written under s.mu, so nothing revokes jti
+	// between the check and the write.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if revoked, _ := s.mem.IsRevoked(jti); revoked {
+		return false, nil
+	}
+	if _, err := fmt.Fprintf(s.file, "%s %d\n", jti, until.Unix()); err != nil {
+		return false, err
+	}
+	return true, s.mem.Revoke(jti, until)
+}
+
+// Close closes the revocation file.
+func (s *FileRevocationStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
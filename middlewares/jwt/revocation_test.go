@@ -0,0 +1,110 @@
+package jwt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRevocationStores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revocation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stores := []struct {
+		name string
+		new  func(t *testing.T) RevocationStore
+	}{
+		{
+			name: "memory",
+			new: func(t *testing.T) RevocationStore {
+				return NewMemoryRevocationStore()
+			},
+		},
+		{
+			name: "file",
+			new: func(t *testing.T) RevocationStore {
+				s, err := NewFileRevocationStore(filepath.Join(dir, "revoked"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return s
+			},
+		},
+	}
+
+	for _, st := range stores {
+		t.Run(st.name, func(t *testing.T) {
+			s := st.new(t)
+			later := time.Now().Add(time.Hour)
+
+			if revoked, err := s.IsRevoked("a"); err != nil || revoked {
+				t.Errorf("IsRevoked(a) = %v, %v before Revoke", revoked, err)
+			}
+			if err := s.Revoke("a", later); err != nil {
+				t.Fatal(err)
+			}
+			if revoked, err := s.IsRevoked("a"); err != nil || !revoked {
+				t.Errorf("IsRevoked(a) = %v, %v after Revoke", revoked, err)
+			}
+
+			if ok, err := s.RevokeIfNotRevoked("a", later); err != nil || ok {
+				t.Errorf("RevokeIfNotRevoked(a) = %v, %v, expected a to be revoked already", ok, err)
+			}
+			if ok, err := s.RevokeIfNotRevoked("b", later); err != nil || !ok {
+				t.Errorf("RevokeIfNotRevoked(b) = %v, %v, expected b to be revoked", ok, err)
+			}
+			if ok, err := s.RevokeIfNotRevoked("b", later); err != nil || ok {
+				t.Errorf("RevokeIfNotRevoked(b) = %v, %v the second time", ok, err)
+			}
+
+			if err := s.Revoke("c", time.Now().Add(-time.Second)); err != nil {
+				t.Fatal(err)
+			}
+			if revoked, err := s.IsRevoked("c"); err != nil || revoked {
+				t.Errorf("IsRevoked(c) = %v, %v after expiry", revoked, err)
+			}
+			if ok, err := s.RevokeIfNotRevoked("c", later); err != nil || !ok {
+				t.Errorf("RevokeIfNotRevoked(c) = %v, %v after expiry", ok, err)
+			}
+		})
+	}
+}
+
+func TestFileRevocationStoreReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revocation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "revoked")
+
+	s, err := NewFileRevocationStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Revoke("kept", time.Now().Add(time.Hour))
+	s.RevokeIfNotRevoked("refreshed", time.Now().Add(time.Hour))
+	s.Revoke("expired", time.Now().Add(-time.Hour))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = NewFileRevocationStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for jti, want := range map[string]bool{"kept": true, "refreshed": true, "expired": false} {
+		if revoked, _ := s.IsRevoked(jti); revoked != want {
+			t.Errorf("IsRevoked(%s) = %v after reopening, want %v", jti, revoked, want)
+		}
+	}
+	if _, err := s.RevokeIfNotRevoked("bad jti", time.Now()); err == nil {
+		t.Error("expected an error for a jti with a space")
+	}
+}
@@ -5,8 +5,8 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/Sirupsen/logrus"
-	"github.com/kildevaeld/valse"
+	"github.com/sirupsen/logrus"
+	"github.com/xwinie/valse"
 )
 
 func NewWithNameAndLogrus(name string, l logrus.FieldLogger) valse.MiddlewareHandler {
@@ -20,8 +20,8 @@ func NewWithNameAndLogrus(name string, l logrus.FieldLogger) valse.MiddlewareHan
 				"remote":  c.RemoteAddr(),
 			})
 
-			if reqID := c.Request.Header.Peek("X-Request-Id"); string(reqID) != "" {
-				entry = entry.WithField("request_id", string(reqID))
+			if reqID := c.RequestID(); reqID != "" {
+				entry = entry.WithField("request_id", reqID)
 			}
 
 			entry.Info("started handling request")
@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/valyala/fasthttp"
+	"github.com/xwinie/valse"
+	"github.com/xwinie/valse/middlewares/requestid"
+)
+
+func TestNewWithNameAndLogrus(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		// requestID is the id set by the requestid middleware, if any.
+		requestID string
+		err       error
+		// status is the expected status of the completed request.
+		status int
+	}{
+		{name: "handled", requestID: "generated", status: 200},
+		{name: "request id", header: "abc", requestID: "abc", status: 200},
+		{name: "invalid request id", header: "a b", requestID: "generated", status: 200},
+		{name: "without requestid middleware", header: "abc", status: 200},
+		// The route renders the error, the middleware sees a response.
+		{name: "failed", requestID: "generated", err: errors.New("failed"), status: 500},
+	}
+	messages := []string{"started handling request", "completed handling request"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, hook := test.NewNullLogger()
+			s := valse.New()
+			if tt.requestID != "" {
+				s.Use(requestid.RequestIDWithConfig(requestid.RequestIDConfig{
+					Generator: func() string { return "generated" },
+				}))
+			}
+			s.Use(NewWithNameAndLogrus("api", l))
+			s.Get("/", func(c *valse.Context) error {
+				return tt.err
+			})
+
+			rc := &fasthttp.RequestCtx{}
+			rc.Request.SetRequestURI("/")
+			if tt.header != "" {
+				rc.Request.Header.Set(valse.HeaderXRequestID, tt.header)
+			}
+			s.GetHandler()(rc)
+
+			entries := hook.AllEntries()
+			if len(entries) != len(messages) {
+				t.Fatalf("expected %d entries, got %d", len(messages), len(entries))
+			}
+			for i, e := range entries {
+				if e.Message != messages[i] {
+					t.Errorf("expected message %q, got %q", messages[i], e.Message)
+				}
+				if id, _ := e.Data["request_id"].(string); id != tt.requestID {
+					t.Errorf("expected request_id %q, got %q", tt.requestID, id)
+				}
+				if e.Data["method"] != "GET" {
+					t.Errorf("expected method GET, got %v", e.Data["method"])
+				}
+			}
+			completed := entries[1].Data
+			if completed["status"] != tt.status {
+				t.Errorf("expected status %d, got %v", tt.status, completed["status"])
+			}
+			if _, ok := completed["measure#api.latency"]; !ok {
+				t.Errorf("expected the latency to be measured, got %v", completed)
+			}
+		})
+	}
+}
+
+func TestLogrusLogger(t *testing.T) {
+	failure := errors.New("failure")
+	tests := []struct {
+		name string
+		log  func(l valse.FieldLogger)
+		want logrus.Fields
+	}{
+		{
+			name: "field",
+			log:  func(l valse.FieldLogger) { l.WithField("a", 1).Info("x") },
+			want: logrus.Fields{"a": 1},
+		},
+		{
+			name: "fields",
+			log:  func(l valse.FieldLogger) { l.WithFields(valse.Fields{"a": 1, "b": "2"}).Info("x") },
+			want: logrus.Fields{"a": 1, "b": "2"},
+		},
+		{
+			name: "error",
+			log:  func(l valse.FieldLogger) { l.WithField("a", 1).WithError(failure).Info("x") },
+			want: logrus.Fields{"a": 1, logrus.ErrorKey: failure},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, hook := test.NewNullLogger()
+			s := valse.NewWithConfig(valse.Config{Logger: NewLogrusLogger(l)})
+			s.Use(requestid.RequestID())
+			s.Get("/users", func(c *valse.Context) error {
+				tt.log(c.Log())
+				return nil
+			})
+
+			rc := &fasthttp.RequestCtx{}
+			rc.Request.SetRequestURI("/users")
+			rc.Request.Header.Set(valse.HeaderXRequestID, "abc")
+			s.GetHandler()(rc)
+
+			e := hook.LastEntry()
+			if e == nil {
+				t.Fatal("expected an entry")
+			}
+			want := logrus.Fields{"method": "GET", "path": "/users", "request_id": "abc"}
+			for k, v := range tt.want {
+				want[k] = v
+			}
+			for k, v := range want {
+				if e.Data[k] != v {
+					t.Errorf("expected %s %v, got %v", k, v, e.Data[k])
+				}
+			}
+		})
+	}
+}
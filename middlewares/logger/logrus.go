@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/xwinie/valse"
+)
+
+type logrusLogger struct {
+	logrus.FieldLogger
+}
+
+// NewLogrusLogger adapts l to valse.FieldLogger, so it can be used as
+// Config.Logger and handlers get logrus entries from Context.Log().
+func NewLogrusLogger(l logrus.FieldLogger) valse.FieldLogger {
+	return &logrusLogger{l}
+}
+
+func (l *logrusLogger) WithField(key string, value interface{}) valse.FieldLogger {
+	return &logrusLogger{l.FieldLogger.WithField(key, value)}
+}
+
+func (l *logrusLogger) WithFields(fields valse.Fields) valse.FieldLogger {
+	return &logrusLogger{l.FieldLogger.WithFields(logrus.Fields(fields))}
+}
+
+func (l *logrusLogger) WithError(err error) valse.FieldLogger {
+	return &logrusLogger{l.FieldLogger.WithError(err)}
+}
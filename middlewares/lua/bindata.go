@@ -1,6 +1,8 @@
 // Code generated by go-bindata.
 // sources:
 // prelude.lua
+// prelude2.lua
+// test.lua
 // DO NOT EDIT!
 
 package lua
@@ -68,7 +70,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _preludeLua = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x56\xcd\x6e\xdc\x36\x17\x5d\x5b\x4f\x71\x3e\x7d\x0b\x4b\xa8\x46\x49\x16\xd9\x14\x56\x80\xa2\x31\x9a\x45\x8a\x16\x6d\xba\x0a\x02\x81\x43\x5d\xcd\x30\x43\x91\x2a\x49\x79\xe2\x06\xee\xb3\x17\xfc\x91\x46\x9e\x8c\x9b\x00\xdd\x78\x04\xf2\xdc\x73\xff\x0e\xef\xf5\x66\x03\x2e\x99\xb5\xb5\x9c\x58\xb6\xd9\xe0\x47\x3d\x8c\xcc\x89\xad\x24\x1c\x85\xdb\xe3\xed\xc4\xf0\xb2\x7e\x81\x42\x69\x87\x97\xf5\xf3\xb2\xce\xfa\x49\x71\x27\xb4\x8a\x86\xc5\x96\x59\xaa\x20\x94\x70\x65\x06\x40\x6a\xce\x24\x38\x1a\x7c\x7e\x00\x80\xcd\x06\x0c\x8a\x8e\x11\x0d\xa1\xac\x63\x8a\x93\x87\x8a\x1e\x9e\xd5\x9b\x82\xa9\x0e\xee\x7e\xa4\x40\x57\xa2\x69\x70\x3d\xfb\xb9\x86\xdb\x93\xf2\x06\xde\xc6\x83\x1b\x78\x54\x3a\xf1\x9f\x68\xa0\x84\xf4\x07\x24\x2d\x89\xfe\x9c\xca\xb1\xad\xa4\x15\xcf\x66\x03\x3d\x99\x75\x58\x16\x0c\x76\xcf\xa4\xd4\x47\x70\x3d\xde\x43\xf7\x1e\x1e\xd9\x03\xe6\x7f\xc9\x5f\xaf\x0d\x44\x75\x07\xa1\x30\x32\x61\x6c\x72\xd3\xe9\x74\x0f\x80\xbf\x17\x1f\xd0\xe0\x2e\x9d\x90\xea\xd2\x17\xaf\xdb\x14\xee\x9c\x40\xba\xdb\x6c\x82\xb7\x18\xcc\x51\x48\x89\x2d\x85\x93\x81\x1c\x0b\xd1\x07\xbf\x4c\x4a\x08\x67\xa1\xb7\x1f\x89\x3b\x5b\x25\xd3\x50\xbc\x3d\xdd\x47\x4b\xa9\xf5\x01\xd3\xe8\x4f\x84\xf1\x04\x7b\xdd\xf9\xc2\x43\xb8\x3a\x8b\x41\xb4\x42\x75\xf4\x09\x0d\x78\x96\x28\xe8\xd3\xa8\x2d\x81\x81\x6b\x65\x9d\x99\xb8\xd3\x06\xc7\xbd\xe0\x7b\x70\xa6\x7c\x34\x9c\x49\x49\x1d\xb6\xf7\xb8\x09\x61\x2a\x36\xd0\xab\xe2\x86\x99\x9d\x7d\xb5\xea\xfc\xe0\x42\xeb\xfd\xc1\xe0\xea\xb6\xf5\x66\x68\x30\x37\xb3\x08\xb6\xad\xdb\xca\x0a\x75\x5d\xaf\x0c\xf5\xf6\xe3\x62\x69\xc9\x2d\x89\x17\x7a\xfb\xb1\xe2\x65\x12\x4c\xe8\xff\x99\x1e\x02\x62\x26\xf3\x02\x40\xba\xdc\x6c\x30\xb0\x03\xc1\x4e\xc6\x57\x93\x79\x99\xdd\xc3\xba\xa9\xef\xd1\x1b\x3d\x9c\x75\xd8\xab\xc0\xf3\x09\x26\xc5\x5f\xd4\xcd\x0d\x17\x7d\xc4\xf8\x2a\xfb\x8f\xfa\x3c\x86\xe5\x30\x04\xb2\xa4\x75\xea\x6e\xfa\x31\xe4\x26\xa3\x7c\xa2\xab\x43\x5e\x27\x49\xfb\x9f\x74\x60\x5b\xb6\x2e\x99\x25\xd9\x57\x38\xf8\x10\x67\xe2\x54\x6b\x34\xd8\xad\x4b\xe5\x91\x33\xe4\xb8\x17\x92\x30\xa0\xd3\x38\x29\x53\xf4\xde\xa8\x89\x64\x21\x87\x39\x2a\x67\x26\x5a\x29\xd5\xb7\x0f\x0d\x86\x28\xd8\x2f\x84\x9c\x8c\x7a\x26\x1f\xc9\xf8\x51\xdf\x78\x85\x21\xce\x84\x84\xe6\x99\x87\x65\xbf\xe9\xc9\x91\xf1\xe2\x0b\xf3\x63\x49\x93\x95\xc8\xae\x58\x2d\x3a\x34\x78\xee\xbf\x8c\x07\xda\x28\x0a\x52\x5d\x99\x65\xa7\xd9\x13\x49\xbe\x0f\x90\x22\x6a\xbc\xc2\xc8\xdc\xbe\x42\xaf\xca\xec\xca\x57\x22\x52\xcd\x5f\xdf\xe1\x45\x3a\x8e\xbc\xef\xd3\x85\x7f\xa9\xbd\xca\xae\xda\x96\x1b\x62\x8e\xda\x4b\x9c\x09\x5b\xc6\x0c\xce\xa3\xd8\x91\x2b\xce\x7c\xa7\xc8\xf2\x9f\x6e\xdf\xe5\xeb\xc0\x2e\xda\x8f\xda\x3e\x49\xf0\xeb\x2f\xbf\x7f\x0b\xc3\xf4\x34\xc1\x1f\xdf\x60\xdf\x91\x24\x47\x4f\x51\xbc\xbe\x7d\x7b\xfb\xee\xf6\xeb\x2c\x7b\x62\xdd\x89\x03\x8f\x49\xde\xdc\xfe\xf0\xfa\xeb\x14\x93\xa5\xe2\xbf\x36\x70\x10\x5d\x27\xe9\xc8\x4c\x7c\x10\x4f\xf7\xcd\x19\xb1\xdb\x91\x29\x44\x57\xc1\xd0\x9f\xfe\x8f\x2d\xb3\xab\x24\xd7\xb5\x2b\xd1\x7d\x28\x4e\x88\x40\xe6\xb7\xe5\x9b\x77\x3f\xbf\x85\x21\xd5\x91\x21\x93\x65\xf1\x55\x2e\x5e\xd8\x38\x92\xea\x5a\x26\x65\xb1\x9d\xfa\x9e\xcc\x32\x1b\xc2\x0e\x69\x5e\x54\x96\x24\x71\x57\xe4\xff\xcf\xe3\xd5\xbc\x45\xc2\x03\xaa\x85\xb2\x64\xdc\x62\x5b\x24\xb4\x88\xd8\xd2\x13\xf9\x48\x42\x34\xd1\xf5\x9d\x16\x5d\xeb\xd8\x2e\x3c\x9a\x0c\x10\xc3\x0e\x4d\x78\xda\x7e\x55\xf8\x31\xef\x78\x5d\xd7\xd9\xc3\x17\xb1\x6e\x27\x21\x83\x69\xe1\xd8\xae\xf5\xb3\xbd\x82\x1e\x5d\x18\x38\x11\x1a\xc3\xf0\xc4\xf9\x4d\x5e\x61\x86\xf9\x79\x3d\x2f\xdc\x60\xe0\xc7\x4b\x1e\x12\xc8\x4f\x43\xd2\x27\x7c\x58\x2f\xcd\x08\x5d\x96\xe6\xcc\x70\x28\xf1\x77\x83\x5c\x4d\xc3\x96\x4c\xbe\x1e\xb2\xb8\x54\xce\x1c\x79\x85\x43\x85\xeb\x26\xbf\xae\x70\x57\xe1\x3a\xbf\x3e\x9b\xbd\xe9\x37\x14\x29\xf8\x59\x6a\xf4\x7e\x4e\xe1\xc3\xc9\xcf\x45\x1f\xcf\x5e\xe5\xa1\xd6\x69\xd6\x5d\x04\x45\xc8\xb7\x94\xe2\xa2\xfd\xa4\x46\xc6\x0f\xd1\x2a\x12\x2d\xde\x2e\xe2\xe7\xd6\x9c\xd2\xbc\x14\xd4\xcd\xb3\x55\xa3\x96\x20\x53\x29\xe6\xb9\x1f\xa4\xc6\xb5\xe2\x6c\x96\xda\x2c\xf0\xd5\x83\x89\x1a\x6f\xf7\x6e\x90\xe1\x79\x86\x51\xdf\x93\xba\x2b\x7a\x55\x3d\x1e\xfb\x9f\x1f\xaa\xa0\x3d\xe0\xf4\x6f\xc6\xd9\x2a\x9b\x43\x2a\xcf\xb6\xc9\x8c\x3a\x65\xb7\xba\xfd\x37\x85\x7e\xd9\xef\x87\xb2\x5c\x25\xd9\xab\x62\x49\xca\xcc\xeb\x27\x0e\x81\xa2\xcc\xb2\x7f\x02\x00\x00\xff\xff\x30\x11\xa3\x3a\x05\x0b\x00\x00")
+var _preludeLua = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x8d\x57\xdb\x6e\xe3\x36\x10\x7d\xb6\xbe\x62\xea\x97\x95\x51\x59\x7b\x01\x0a\x14\xed\x7a\x81\xa2\x1b\xa4\x05\xb6\x68\xd1\xa6\x7d\x31\x02\x83\x96\x28\x99\x1b\x89\xf4\x92\x54\x12\xb7\xc8\xbf\x77\x86\x43\x5d\x7c\xc9\xee\xe6\x21\xa1\xc8\x99\x33\x67\xae\x64\x96\x4b\x28\x1a\xe1\x5c\xde\x74\x22\x59\x2e\xe1\x67\xd3\xee\x85\x57\xdb\x46\xc2\x83\xf2\x3b\xf8\xd0\x09\xf8\x2e\x7f\x0d\xa9\x36\x1e\x17\xaf\x16\x79\x52\x75\xba\xf0\xca\x68\x56\x4c\xb7\xc2\xc9\x0c\x94\x56\x7e\x91\x00\x40\x63\x0a\xd1\x40\x01\x2b\xf8\xef\x09\x3f\x01\x31\x05\x68\xf9\xc0\xd2\x28\xe7\xbc\xd0\x85\x24\x51\x55\x01\xa1\x92\x2a\x08\x5d\x82\x3f\xec\x65\x80\x5b\xc0\x6a\x05\x2f\x7a\x3b\x2f\xc0\xef\xa4\x26\x05\xd2\x21\xe1\x15\x90\x54\xdc\xa1\x25\xee\x68\xd5\xd0\x86\x6c\x9c\x44\xdc\x13\x28\x2f\xd0\xa1\x09\x0e\x92\x32\x9d\x9d\xd2\x72\xc8\xd2\xed\x44\xd3\x18\xdc\x32\xfb\x03\x98\x8a\xc4\x19\x3d\xc8\x7c\x13\xed\x55\xc6\x82\xca\xee\x91\x09\xec\x85\xb2\x2e\x9a\x29\x4d\x3c\xc7\x9f\x62\xad\x6e\x91\xd2\x7d\xdc\x91\xba\x8c\xab\x22\xdf\x44\xba\xbd\x03\xf1\x0c\x09\x91\x35\x26\xf3\xa0\x9a\x06\xb6\x32\xec\xb4\xd2\x8b\xc0\x3e\xd8\x45\x7e\xa0\xbc\x03\xb3\xfd\x28\x0b\xef\xb2\xa8\x1a\x82\xb7\x93\x07\xd6\x6c\x8c\xb9\x83\x6e\x4f\x3b\xca\x12\xc0\xce\x94\x14\x78\xd4\xcc\x13\x26\xb1\x51\xba\x94\x8f\x48\xa3\x48\x22\x84\x7c\xdc\x1b\x24\x26\xd0\x79\xcc\x90\xed\x0a\x8f\xe6\x1e\x76\xaa\xd8\x41\x21\x34\xb1\xc1\xb4\x36\xb2\x84\xed\x01\xde\x06\x9a\x5a\xb4\xf2\x5d\xfa\x56\xd8\xda\xbd\x9b\x64\xbe\xf5\x21\xf5\xb4\xd1\x7a\xb4\x44\x6a\xb8\xd3\x27\x33\x0d\xba\x1b\xbf\x6d\x32\xc8\xf3\x7c\xa2\x88\x3e\x0d\x9a\x4e\xfa\xc1\xf1\x14\x0f\xb2\x62\x11\x0b\x26\xe4\xff\xa4\x1e\x82\x44\x0f\x46\x05\x00\xf1\x10\xfd\x6a\xc5\x9d\x04\xd7\x59\x8a\xa6\xa0\x32\x3b\x80\xf3\x5d\x55\x41\x65\x4d\x7b\x92\x61\xaa\x02\xc2\x53\xa2\x51\xff\xca\xb2\x4f\x38\x5a\x0d\x32\x14\x65\x5a\xe4\xa7\x1c\x86\xcd\x40\x64\x70\x6b\xcc\x6e\xfc\x63\xa5\xef\xac\x26\x47\x27\x9b\x45\x1e\x4b\x9a\xfe\xc4\x0d\xb7\x11\xd3\x90\x39\xd9\x54\x19\xdc\x11\xc5\x1e\x38\xc6\x1a\xa5\xea\x69\xa8\x48\xb2\x17\xc1\xe4\x61\xd5\xb4\x58\x98\x30\x56\x26\xfa\xd2\x52\x47\x04\xb0\xe0\x43\xcf\x0a\x73\x2e\x27\x95\x4a\xe9\x43\xf4\x96\x0b\xf6\xac\x90\xa3\x52\x25\x9a\xa3\x32\x3e\xca\x5b\x91\x61\x05\x2c\x26\x8e\x17\x09\x89\xe1\x94\x59\xaf\x13\x76\x40\x70\xc6\xf9\xc3\x9a\xce\x4b\x47\x3b\xc9\x0c\x8b\x91\xeb\x28\x4b\x66\xe8\x21\x8b\x3d\x8d\xa3\xc7\xca\x5a\x39\x2f\x6d\xda\xaa\xb2\x6c\xe4\x83\xb0\x72\x91\xcc\x18\x87\xca\x2d\x30\xc8\x51\x53\xa7\x02\x0f\xc4\xba\xa1\x86\x1c\x85\x03\x93\x11\xad\xd3\xbf\x0d\x47\x2e\xb5\xf2\x53\x86\x16\x30\xd8\x33\x6a\xba\x0d\xc6\x7e\xec\x76\xb1\x00\xea\xf5\x59\x34\x66\x03\xb9\xbb\xa9\xce\x2c\xce\x35\x3a\x0a\x55\x32\x9b\xcd\x8e\xe2\x35\x9b\x91\x75\xfe\x35\x89\x7e\xe0\xb4\x5c\xde\xde\x26\xc9\x9f\x14\x0a\x4b\xfd\x19\x46\xec\x50\x09\x68\x1c\x9d\xc9\x55\x89\x47\xaf\x68\x35\xc6\xec\x89\xd4\x17\x14\x5d\x60\xed\x1f\x44\x59\x62\xad\x1b\x24\x05\x95\x86\x0e\x3b\xde\xc6\x51\x8c\xfa\x54\xcc\x6c\x3b\x94\x01\x6e\xe5\x70\x8d\x68\x7b\x1c\x84\x65\xc9\xb3\x83\xb0\x7a\xd3\x28\x65\x82\xa0\x65\x6a\x3c\x6e\x24\xf6\x55\x61\xa5\xf0\x38\x18\xa8\xa1\xb2\x38\x32\xbc\x55\x75\x2d\x6d\x80\x6e\x27\x37\xc6\xc8\x2c\xad\xf4\x90\x30\x6b\x0c\x05\x91\x8a\x37\x8f\xf0\x18\x76\xfa\xc4\xf8\xe0\x19\xfb\xdb\xaf\xbe\x85\xd7\x71\x9b\x9d\x5f\xc7\x03\x4a\x70\xa5\x87\x88\xc6\x5d\x2e\xb9\x53\x02\x41\x33\xe5\xd1\x98\x61\x62\xfd\x2e\x83\x40\x08\xe7\x55\x70\x67\x73\x49\x82\x18\xf5\xdc\xf9\x2b\xaf\x29\x64\x1b\x85\x81\xbf\x68\x07\x0b\x30\x9d\xa0\x07\x00\x46\x9e\x5f\x5f\xdd\xcc\xa7\xa6\x2f\xea\x53\x1b\x3c\x07\xf0\xc7\xef\x7f\x7d\x0d\x42\xf7\x3c\xc0\xdf\x5f\xa1\x5f\xca\x46\xa2\xf0\x33\x10\xef\xaf\x3e\x5c\xdd\x5c\x7d\x19\x65\x27\x45\x39\x62\xc0\x31\xc8\x2f\x57\x3f\xbd\xff\x32\x44\xe7\x64\x7a\x9c\xa2\xb1\x9b\xd3\xe3\xcc\xcc\xe7\x97\xb3\x33\xb6\xc6\xa8\x3a\x4c\x12\x7a\x00\x4c\xb6\xb9\x8e\x8d\x6e\x0e\x34\x1d\xf0\x4e\xde\x51\xad\x5f\x1b\x40\x1e\x8e\x90\x94\x27\x23\x92\x1e\x48\xff\x50\x57\xe7\xe3\x04\xc9\xe1\x83\xba\x8b\xef\xa7\x91\x3d\xf9\x36\xb6\x1c\x4d\x5b\x6f\x08\x68\x1f\x27\x31\x9e\x7d\xea\x24\x8e\x3d\xa3\xcf\x3b\x66\xe2\x2b\xdd\xbb\xa7\xd5\xfa\x6c\x28\x48\xf8\xcc\xfb\x10\x96\x81\x8a\x00\xfe\xc6\x07\x4f\x9c\x26\xf8\x0e\xb2\x4a\xd7\x81\x14\x65\x05\xf6\x56\x56\xea\xb1\x7f\x66\x10\xd2\x68\x30\xcc\x0b\x6c\xff\xe0\x6d\x40\x22\x67\x73\xf8\xb5\x22\x7f\xf1\x46\xad\xd5\xbd\xa4\xb7\x07\xad\xe3\x1b\x22\xc8\x46\xa4\xa0\x92\xe3\x8a\x3e\xae\x44\x11\x41\xf8\x45\x76\x1f\x22\x1b\xe6\x12\xb4\xa6\xd3\x34\x67\x30\x28\x44\xcc\x49\x7b\x2f\x6d\x46\x93\xc2\xe8\xf0\x28\xda\x23\x1b\xed\x07\xcc\x0c\xf0\xbe\x67\xe6\x71\xb4\x1d\xb1\xc6\xac\x82\xa8\xe2\x28\x43\x08\xdc\xea\x23\xc0\xe9\x4a\xc2\x23\x91\x04\x6a\x9e\x8b\x15\x8e\x7f\x4c\x3a\x45\x21\xd4\x05\xdd\x0d\xc3\x44\x74\xfd\x83\x31\xfa\x73\x36\x09\x68\x3b\x65\x3a\x31\x7b\x3c\xfc\x6a\x1c\x5b\x81\x5d\x7a\x61\xfe\x65\xd1\x81\x69\xae\x19\xa9\x1e\x8a\xbb\x97\x39\xab\x79\xba\x87\x2a\xdd\x5f\x41\x95\x4e\xeb\xc5\xf1\xa5\x53\x5f\xee\xb4\x38\xbc\x53\x82\x9e\x5c\x6b\x4c\xd7\xa1\xca\xd1\x73\xce\x60\x52\xb4\xbf\xc1\xf7\x76\x06\x5b\x53\x1e\x90\x06\x3e\x00\xba\xfe\x22\xe4\x8f\xc9\x3d\xe8\x72\x82\xf8\xbc\x9e\xe4\x5b\xf2\x33\xd2\x8b\x93\x4b\xd4\xe5\x1f\x1d\x7a\x30\x21\x86\xa5\xd3\xc9\x29\x28\xf3\x67\x53\xd2\xd2\xe5\xca\xd5\x45\x8a\xb9\xd4\x85\x29\x25\x2b\x45\xe6\x24\xd3\xd3\xc6\xb5\xb1\x29\xfe\xce\xe0\xcd\x60\x7a\x16\xa8\xcd\xc5\x7e\xdf\xa8\x42\x90\xd1\x97\x84\xf5\x23\x14\xd8\x3f\xf8\x12\x5a\x75\xbe\x5a\x7e\x3f\x3f\x73\x2f\x28\x63\x71\x11\xeb\x9d\x6f\x39\xa6\xe1\xff\x0f\x1f\xda\x0e\x73\x8f\x85\x5c\xf2\x48\x1a\x92\x13\x7a\x86\xf7\x37\xa4\x95\x05\x0c\x1e\x53\x58\x00\x4d\x57\xca\x37\xf4\x4f\x1c\x94\x58\x0c\x58\xcd\x39\x87\x25\x18\x98\x84\xe5\x42\x8a\xf8\x7f\x25\x0a\x2a\xbd\x0c\xe7\xbd\xe8\x7c\xf0\x9e\xce\xe8\x02\x1e\x8d\xa7\x47\x39\x88\x81\xf0\xf2\xd1\xbf\xa4\xd3\xf3\x08\x78\xc3\xbe\xb1\xde\x69\x2c\x62\x39\x8e\xf5\xef\xd6\x78\x9d\x4f\x9e\x54\xa1\x4c\xb9\x85\xfb\x27\x11\xd7\x6a\x36\x3a\x56\x67\xb1\x79\x27\x3d\x53\xf7\xed\xb4\x8a\x67\xb4\x15\x87\xd9\x2a\x8a\xd1\x56\xdf\x33\x83\x1c\x0f\x50\x2a\x8f\x45\x7c\x56\x0d\x40\x6c\x38\x5d\xfc\x0f\x0d\x6f\x83\x91\x39\x0f\x00\x00")
 
 func preludeLuaBytes() ([]byte, error) {
 	return bindataRead(
@@ -83,7 +85,47 @@ func preludeLua() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "prelude.lua", size: 2821, mode: os.FileMode(420), modTime: time.Unix(1499237289, 0)}
+	info := bindataFileInfo{name: "prelude.lua", size: 3897, mode: os.FileMode(420), modTime: time.Unix(1792306595, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _prelude2Lua = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xbd\x17\xcb\x72\xdb\x36\xf0\xae\xaf\xc0\x30\x4d\x24\x4e\x58\xa7\x69\xda\xce\x34\xb1\x7d\xed\x47\xa8\x2e\x07\x22\x41\x19\x23\x10\x64\x40\x90\x8e\x27\xe3\x7e\x7b\x77\xf1\x58\x80\xb2\x9c\x74\x7a\xe8\xc1\xd6\xee\x62\xb1\xef\x07\xa8\x86\x86\x2b\x26\xa6\x86\x8f\x62\x62\x37\xec\xeb\x86\xb1\x7d\xf1\xa6\xb8\x03\xb8\x78\xc3\xfb\xf1\x53\x51\x39\xd2\x75\x20\x29\x1b\x29\xb7\x81\x72\x8c\x94\x6d\xb1\xf5\x94\xcf\xf3\x40\x5c\xdb\xc0\xf5\xea\xc3\xef\x9f\x8a\xcd\xd3\xc6\xab\xaa\xef\x6d\xaf\x80\xde\xcd\xba\xb1\x72\xd0\xbb\xc9\x9a\x12\xf8\x8d\xb0\xb3\xd1\x0c\x51\xa9\x8f\x57\xc7\x69\x3e\xec\xec\xe0\x31\xc7\x53\xb1\x62\xff\xe6\xfa\xf6\x4f\x50\x55\x54\xd1\xee\xb2\xdc\x08\xdd\x6e\x94\xf3\x05\x25\xd7\xbd\x0d\xbe\xd4\x75\xbc\xbe\xd2\x26\x54\x87\xea\x48\x21\x12\xae\xf0\x26\x10\x41\x54\xe5\x6e\x36\x83\x6e\xb8\xcd\xef\xf1\x8a\x1d\x56\xf7\xc8\x36\x5e\xb2\xab\xab\x84\x3a\x2e\xb4\xe9\x69\x63\xf8\xc3\x77\xbc\x9d\x84\xed\x85\xe5\x96\x1f\x94\xd8\x7d\x75\xe2\xc3\x85\x95\xeb\x70\xf0\x54\x45\xf7\x72\x8f\xe5\xe4\x14\x6c\xc2\x6f\xae\x68\xc9\xd4\x1c\x73\x35\x4b\xc9\x6e\x6e\xa2\xb0\x4c\xd6\x32\xc8\xb6\xb6\xfc\x38\x6d\xda\x01\xae\x7a\x62\x6d\x0f\xaa\xfe\x09\x23\xca\x9e\x12\x51\xc9\xc9\x7a\xaa\x33\xb9\xe0\x46\x70\x97\x74\x80\x0f\x7c\x12\x04\x9b\x08\x35\x83\x8a\xa0\xe8\x0f\xa2\x8d\xc8\x3d\x71\xc8\xfe\x48\xa0\x1e\x67\x1b\x11\x25\xf5\x29\xc2\xe8\x44\x84\x47\x6e\x78\x1f\x91\x69\x98\x4d\x43\x6a\xad\xe1\x0d\xdd\x79\x00\x23\x30\x7e\xf0\xd7\x0d\x86\xd5\x52\xb7\xe2\x8b\x33\xfe\x7d\xc5\x5e\x45\x57\x9c\xcb\xd1\x3f\x4c\x7c\x38\xd8\x47\xfe\x3b\x77\xee\xc3\xb1\xb7\x58\xd8\xd6\xcc\x22\xa4\x9a\xa5\xe0\xe1\x55\xc7\x94\x45\x56\x28\xd1\x0b\x6d\xff\x4b\x60\xa3\x17\xfc\x90\x62\xc9\xdb\xd6\x88\x69\x22\xd4\x58\xd9\x28\x72\x9e\x4f\xb2\x4d\xc8\xdc\xca\x81\xb2\x41\x00\x68\x3b\x61\xa7\xa6\x44\x0d\xed\x23\xc1\xb3\xb5\x83\xa6\xc4\x71\xbd\xf0\x29\x61\x23\x16\x57\xca\x6a\x52\x05\x19\x3e\x9a\x61\x1e\x23\xde\x52\x8e\x5b\xa1\x12\x68\xb9\x54\x24\xae\x95\x0b\x81\x89\xc7\xa6\x4a\x89\x50\x27\x85\x6a\xa1\x59\x12\x7e\x9c\x0d\xa9\xee\x06\x70\xc5\x24\xcc\xd0\xbd\xfb\xf7\x04\xfd\x4c\xd0\x07\x82\x7e\x21\xe8\x57\x82\x7e\x23\x48\xf0\x36\x87\x93\x06\xec\x1d\xaa\x55\xaa\x53\x7e\x48\x7e\x2a\x71\x84\xf4\xa7\x12\xa6\x02\xe6\x92\x82\xa7\x39\x39\x9f\x9a\x63\x18\xed\x2a\x8a\xc3\x2a\xde\x44\x1e\x93\xf3\x93\x68\x72\x16\x18\x67\x40\x20\xac\xe7\x8a\x64\x4f\x23\x4f\x6c\xd6\x0c\xfa\x98\xb0\xc7\x54\x40\x30\x78\x13\xd8\xf7\xdc\x3c\x26\x94\x0c\x70\xa3\x84\x90\xbc\x7c\x2c\xb9\x6d\xc5\x17\x9b\x4f\x06\x8b\x79\x22\xe4\x3e\x41\x59\xa0\xad\xec\x93\x58\x69\x33\x1d\x14\xfd\x99\x00\xf2\x6c\x81\x92\x1f\xfe\x87\x2e\x8f\x8d\xbc\x6e\x72\x54\x68\x99\xd4\x6c\xe4\xd2\x4c\x3b\x1a\x05\xa5\xd7\x17\x2f\x65\xf2\xd2\x68\xc0\x15\x81\x71\x8a\xb3\x03\xfb\xde\x65\x23\xb2\x3e\x05\x46\x3e\x8e\x70\xab\x6e\xee\xa5\x82\xee\xd7\x9b\x33\x3c\x9f\xfc\x87\xb9\xeb\x84\xa9\xd8\x52\x91\x78\xdc\x05\xb2\x8b\xeb\x02\x57\x00\x44\x5d\xaf\xd6\x19\x26\xf4\x4a\xea\x49\x18\x9b\x24\xb8\xc5\xe8\x16\x9a\x9a\x04\x08\xb0\x8f\x63\x5c\x20\xa1\x04\x92\xa0\x0b\x71\x5f\x62\xc4\x63\xcc\x9d\xb9\x70\xb8\x9c\x45\x9c\x9d\xfb\x47\x26\x38\xc2\xda\x91\x94\x0d\x67\xd3\xc2\xfe\xbe\x61\x5a\x42\x88\x74\xeb\x91\x8e\xc3\xc9\xbf\xf3\x30\xca\x75\x97\x23\x82\x1e\x82\x33\xd9\x7b\x05\x28\x71\xad\x3f\xcf\xdd\x86\x84\x64\x49\x80\x7c\xd6\x9a\xf7\x22\xdb\xc2\xeb\xd5\x1c\x43\x02\x9d\x98\xaf\xfb\x70\x06\x7e\x85\x87\x50\x07\x81\x8a\x8f\x22\x35\x3c\x08\x13\x1f\x43\xd7\xef\x0a\xf7\xf0\x08\x8a\x2a\x8c\x38\x96\x4c\x96\x5b\xb0\xd8\x98\xc1\xec\xdc\xc4\xfa\xc8\x56\xfc\x08\x43\xf6\xd0\xec\x9e\x3f\x32\x3d\x58\x06\xaf\x1e\x18\xcf\x9a\x9d\x49\xce\x63\x9e\x5e\x2e\xd6\x3c\x0b\xc8\x61\x86\x5c\x61\x25\x6f\x08\xba\x14\x92\x8a\xc1\x54\x9b\x4a\xda\x7a\x3e\x17\x69\xe9\x5d\x87\xb6\x8e\xfc\xa1\xaf\x63\xf9\xb9\xcb\x79\x05\x62\xea\xd0\xfc\x58\xdd\x9e\x81\x82\xe0\x95\x9c\xc4\xe3\xe4\xd0\xb3\x8a\xac\x79\xd3\xcc\x7d\xb6\x89\xb3\x23\x25\xb4\x2f\xe5\x40\xc6\x0a\x3f\xa5\x46\xf7\x7a\x48\x5e\xb2\xf0\xe4\xcd\xf3\x49\x2b\xf2\x74\xb8\xe9\x12\x34\xee\xbd\x7c\x1c\x0a\xa7\xfc\x38\x6a\x0d\xc0\x5b\x52\x9f\x72\x90\x43\xe8\x18\x72\x07\xa9\xab\x5c\xf9\x92\x9f\x06\x28\x78\x64\x2b\x5f\xea\x53\x27\xe3\x2c\x30\x27\xb4\x0b\xe8\xcf\x3a\x15\xbc\xc4\x68\xe7\xe5\x79\x82\x72\xfc\x6b\xff\xfa\xe1\xe3\x8f\x77\x6f\x7f\x28\xca\xb5\xc7\xab\x12\x94\xf0\x9a\x50\xb2\x65\xdc\xc2\x75\x78\x69\x08\xe6\x6a\xd1\xd5\x5b\x94\x08\xfb\x9b\xdb\x5d\xf1\xfa\x33\x3c\xf3\x4f\x65\xf9\xcc\xe3\xf0\x5c\x05\xfb\x30\x03\xfb\x53\x66\xd8\x82\x81\xc7\x26\x58\x9b\x70\xb1\xf5\x0b\xaf\xf5\x44\x0a\xb2\x71\x72\x36\x41\xbe\x2b\x03\xff\x6d\x6f\x8a\x2d\xfe\xae\xc7\x86\x3b\x29\xb6\xe7\x5e\x84\x09\xe6\xfe\xa3\xd2\xb8\x33\xf6\xb1\xe8\xef\x92\xf6\x17\x34\xbf\xbb\x2d\xe2\x64\xfe\x06\x9b\x67\x7a\x79\xbe\x62\x08\xab\xf5\x1a\xca\x6c\x58\x4d\x45\x9a\x07\xe5\x37\xf4\x9d\xcd\x0e\x37\x65\x82\xa1\xce\xd9\x30\x3e\xe2\xa7\xd1\xce\x4b\xf1\xdf\x5b\x41\xca\xea\x9b\x4e\xe8\x25\xff\xa4\x73\xb5\x78\xfe\x3d\x57\xb1\x34\xa6\x42\x75\xd2\xd2\x4d\x7e\xd4\x7f\x04\x24\xac\x8b\x2c\xbb\xc1\xa8\xc8\x71\x69\xde\x91\xc2\x38\xbb\xb2\x33\x9a\x76\xbb\xf5\x78\xcb\xd3\x0c\x5f\x83\xf0\x23\xcc\xb3\xef\xb4\x4e\x23\x2b\xbc\x6d\x3b\x70\x15\xb0\xea\xd2\x47\x21\x84\x0b\xee\xc4\xa0\xf9\xcf\x41\x1f\x99\xf2\xd2\x1c\x75\x83\xec\xa5\x8c\x77\x7a\x57\x66\x5b\xe9\x52\x02\x5c\xfc\xff\x01\xc7\xea\x23\x8f\x20\x10\x00\x00")
+
+func prelude2LuaBytes() ([]byte, error) {
+	return bindataRead(
+		_prelude2Lua,
+		"prelude2.lua",
+	)
+}
+
+func prelude2Lua() (*asset, error) {
+	bytes, err := prelude2LuaBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "prelude2.lua", size: 4128, mode: os.FileMode(420), modTime: time.Unix(1792304954, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _testLua = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x9d\x54\xdb\x8e\x9b\x30\x10\x7d\x0e\x5f\x31\x42\x8a\x04\x95\x83\xfa\xbc\x52\xfe\xa2\xef\xc8\x81\x81\x58\x71\x6c\xe2\xcb\xee\x46\xab\xed\xb7\x77\xc6\x26\x81\xa6\x6d\x5a\xf5\x05\xb0\x67\xe6\x9c\x33\x67\x6c\x76\x3b\x08\xe8\x43\xa3\xa3\x04\xe5\x21\x1c\x31\xad\x41\xab\x83\x93\xee\x0a\x76\x80\x2f\xed\x3d\x63\x50\x1a\xfd\x0b\x67\x5d\xc1\xe1\xa8\x7c\x40\x97\xf2\x7d\xb1\xdb\xc1\x9b\x0a\xc7\xb4\xaa\x8c\x3c\xa3\x80\xc1\xd4\x20\x4d\x0f\xdd\x11\xbb\x13\xe5\xfb\xa8\x83\x9f\xb3\x88\x47\x7a\x8f\x2e\xc0\x10\x4d\x17\x94\x35\x1e\x0e\xa8\xed\x5b\xc3\x48\xdf\x28\x4c\x7c\x49\xc9\x24\xbb\x93\x1c\x69\x6d\x65\xef\x41\x05\x50\x26\x95\xfb\xc0\x71\x70\xd1\x18\x65\xc6\x45\x79\xd2\x28\x52\x62\x52\x65\x2c\x63\x10\x11\xb5\xc2\x49\x93\x43\x1d\x7b\x6c\x8a\x42\xdb\x4e\xea\x2c\x1f\xf6\xf0\xf1\x59\x14\x37\x2d\x0f\x5d\x14\x9b\x20\x0f\x1a\x1b\x65\x58\x71\x95\x2a\x04\x7c\x00\x27\x50\xe5\x2d\x8f\x3e\xe9\xf1\x59\x17\x68\xfa\x15\x56\xdb\x92\xc6\xe4\xa1\xaf\x1c\x4e\xd6\x05\x02\x9c\xb9\x9d\xec\xf0\x40\x0d\x52\x69\x8f\x87\x38\x26\xbf\xd2\x57\xb3\xc4\x2c\x79\x6c\x7d\x70\xd4\x66\xb1\x19\x68\xd5\x0a\x48\x36\xa8\x49\x2a\xe7\xb3\x9e\x1a\x7a\x5b\x6c\x66\x5c\x7b\x12\x80\xce\x11\xea\xfb\x44\x6b\x5d\x85\x66\x30\x62\xa1\x23\x01\x9b\x2c\x85\x22\x59\xbe\x3d\x25\xee\xb2\x5c\xd3\x55\x04\x52\x53\x32\x37\xb4\x59\x19\x95\x1a\x9c\xe7\xb4\xe8\x64\x6e\x1e\x09\xbb\x3c\x48\x1a\x43\x3f\xcf\x98\x5c\x10\xf4\x09\x68\x46\x65\x28\xa1\xb3\xd1\x04\x16\x68\x1d\xa3\x68\x7c\x45\xed\xa1\x57\xc3\x80\x0e\x4d\xd0\xd7\x66\x9e\xcd\xdd\x43\x86\xab\xce\x7e\x14\x74\x8c\xa4\xb7\x3c\x12\x35\x00\x6d\x30\x99\x49\xdd\xf0\x36\xa9\xe3\xbd\xa6\x81\xf2\x05\x4a\x7e\xe7\xfd\xb9\x83\xc4\x58\xe5\x2d\x01\x5f\x1f\x07\x95\xc5\xb6\x78\x89\x52\x57\xb2\x0b\xf4\x22\x1b\xdf\x27\xec\x02\xf6\x82\x91\x33\x6d\x0e\xc1\xf7\xfd\x3d\x78\x53\xb1\xc8\xcc\xfe\x35\x34\xad\xb3\x0c\x55\x79\x4f\xdc\xd2\xc1\x19\xe9\x44\x6e\x7d\x29\x56\x36\xcf\xe1\x7a\xb5\x97\x59\xea\x9b\xfd\xbf\x95\x4a\x67\xfb\x41\x6e\x34\x7f\x16\xbc\xdf\xaf\xc2\xff\x2e\x59\xc2\xab\xd4\x11\xc1\x52\x05\x1d\x8d\xa3\x34\x0f\xea\x17\xd0\xe7\x6a\x83\x8b\x58\x25\xac\x95\x36\xbe\x9e\x19\xff\x17\x41\x6b\x09\x5c\x0b\x73\x2d\xfb\x97\x86\x7b\x57\x90\x02\xcf\x9d\x22\xd0\x47\xea\x4c\x4b\x63\xa4\xe0\x33\x76\x0a\xff\x1f\x29\xf9\xd8\x1d\x2b\x4a\x17\xf4\x07\x0a\xf4\xaf\x34\x2b\xf6\x70\x9d\x90\x63\x35\x2b\x28\x33\x66\xba\x7c\xec\xc8\x6d\x16\xca\xf4\x3f\xd5\xd7\x7f\x1d\xdb\xf6\x42\x3f\x02\xba\x63\x8c\x92\xf8\x61\x7b\x59\x0f\x8b\x19\x17\xb8\x95\xfa\x1f\xfd\xb1\x43\x7a\x0a\x06\x00\x00")
+
+func testLuaBytes() ([]byte, error) {
+	return bindataRead(
+		_testLua,
+		"test.lua",
+	)
+}
+
+func testLua() (*asset, error) {
+	bytes, err := testLuaBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "test.lua", size: 1546, mode: os.FileMode(420), modTime: time.Unix(1792305053, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -140,7 +182,9 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"prelude.lua": preludeLua,
+	"prelude.lua":  preludeLua,
+	"prelude2.lua": prelude2Lua,
+	"test.lua":     testLua,
 }
 
 // AssetDir returns the file names below a certain
@@ -183,7 +227,9 @@ type bintree struct {
 	Children map[string]*bintree
 }
 var _bintree = &bintree{nil, map[string]*bintree{
-	"prelude.lua": &bintree{preludeLua, map[string]*bintree{}},
+	"prelude.lua":  &bintree{preludeLua, map[string]*bintree{}},
+	"prelude2.lua": &bintree{prelude2Lua, map[string]*bintree{}},
+	"test.lua":     &bintree{testLua, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
@@ -0,0 +1,243 @@
+package lua
+
+import (
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"github.com/xwinie/valse"
+)
+
+// result collects what a script decided about the response, besides what
+// it wrote directly.
+type result struct {
+	err error
+}
+
+// errValue returns the message of err, or nil. Bridge functions return
+// errors the Lua way, as a second result.
+func errValue(err error) interface{} {
+	if err != nil {
+		return err.Error()
+	}
+	return nil
+}
+
+// paramNames returns the names of the ":name" and "*name" segments of a
+// route path.
+func paramNames(path string) []string {
+	var names []string
+	for _, seg := range strings.Split(path, "/") {
+		if len(seg) > 1 && (seg[0] == ':' || seg[0] == '*') {
+			names = append(names, seg[1:])
+		}
+	}
+	return names
+}
+
+// saveFunc saves an uploaded file to path.
+type saveFunc func(fh *multipart.FileHeader, path string) error
+
+// newSaveFunc returns the function behind file.save, or nil if scripts may
+// not save files. With LuaOptions.UploadDir the path is relative to it and
+// may not be absolute or contain "..". Without it sandboxed scripts, those
+// run with LuaOptions.Libraries set, cannot save files.
+func newSaveFunc(options LuaOptions) saveFunc {
+	if options.UploadDir == "" {
+		if options.Libraries != nil {
+			return nil
+		}
+		return fasthttp.SaveMultipartFile
+	}
+	dir := options.UploadDir
+	return func(fh *multipart.FileHeader, path string) error {
+		if path == "" || filepath.IsAbs(path) || path[0] == '/' || path[0] == '\\' {
+			return fmt.Errorf("cannot save to %q: path must be relative to the upload directory", path)
+		}
+		for _, e := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+			if e == ".." {
+				return fmt.Errorf("cannot save to %q: path may not contain \"..\"", path)
+			}
+		}
+		return fasthttp.SaveMultipartFile(fh, filepath.Join(dir, path))
+	}
+}
+
+// createRequest returns the req table passed to scripts: req.path,
+// req.method, req.host, req.ip, req.id, req.params, req.param(name),
+// req.header.get(key), req.query.get(key), req.query.all(key),
+// req.cookie(name), req.cookies(), req.form(name), req.file(name) and
+// req.body(). The file table has save(path) only if save is not nil.
+func createRequest(ctx *valse.Context, params []string, save saveFunc) Table {
+	p := Table{}
+	for _, name := range params {
+		if v, ok := ctx.UserValue(name).(string); ok {
+			p[name] = v
+		}
+	}
+
+	return Table{
+		"header": Table{
+			"get": func(key string) string {
+				return string(ctx.Request.Header.Peek(key))
+			},
+		},
+		"url": func() Table {
+			url := ctx.Request.URI()
+
+			return Table{
+				"query": Table{
+					"get": func(key string) string {
+						return string(url.QueryArgs().Peek(key))
+					},
+				},
+			}
+		},
+		"query": Table{
+			"get": func(key string) string {
+				return string(ctx.QueryArgs().Peek(key))
+			},
+			"all": func(key string) []string {
+				var out []string
+				for _, v := range ctx.QueryArgs().PeekMulti(key) {
+					out = append(out, string(v))
+				}
+				return out
+			},
+		},
+		"params": p,
+		"param": func(name string) string {
+			v, _ := ctx.UserValue(name).(string)
+			return v
+		},
+		"cookie": func(name string) string {
+			return string(ctx.Request.Header.Cookie(name))
+		},
+		"cookies": func() Table {
+			out := Table{}
+			ctx.Request.Header.VisitAllCookie(func(k, v []byte) {
+				out[string(k)] = string(v)
+			})
+			return out
+		},
+		"form": func(name string) string {
+			return string(ctx.FormValue(name))
+		},
+		"file": func(name string) (Table, interface{}) {
+			fh, err := ctx.FormFile(name)
+			if err == fasthttp.ErrMissingFile {
+				return nil, nil
+			} else if err != nil {
+				return nil, errValue(err)
+			}
+			file := Table{
+				"filename": fh.Filename,
+				"size":     fh.Size,
+				"type":     fh.Header.Get("Content-Type"),
+				"read": func() (string, interface{}) {
+					f, err := fh.Open()
+					if err != nil {
+						return "", errValue(err)
+					}
+					defer f.Close()
+					bs, err := ioutil.ReadAll(f)
+					return string(bs), errValue(err)
+				},
+			}
+			if save != nil {
+				file["save"] = func(path string) interface{} {
+					return errValue(save(fh, path))
+				}
+			}
+			return file, nil
+		},
+		"path":   string(ctx.Path()),
+		"method": string(ctx.Method()),
+		"host":   string(ctx.Host()),
+		"ip":     ctx.RemoteIP().String(),
+		"id":     ctx.RequestID(),
+		"body": func() string {
+			return string(ctx.PostBody())
+		},
+	}
+}
+
+// createResponse returns the res table passed to scripts: res.write(str),
+// res.status(code), res.send(contentType, body[, code]), res.json(value[,
+// code]) and res.html(body[, code]) which the prelude adds,
+// res.redirect(url[, code]),
+// res.cookie(name, value[, maxAge]), res.header.get/set/add and the error
+// helpers res.error(code[, msg]), res.badRequest, res.unauthorized,
+// res.forbidden and res.notFound.
+func createResponse(ctx *valse.Context, r *result) Table {
+	fail := func(status int) func(msg ...string) {
+		return func(msg ...string) {
+			r.err = valse.NewHTTPMessage(status, msg...)
+		}
+	}
+
+	return Table{
+		"header": Table{
+			"get": func(key string) string {
+				return string(ctx.Response.Header.Peek(key))
+			},
+			"set": func(key, val string) {
+				ctx.Response.Header.Set(key, val)
+			},
+			"add": func(key, val string) {
+				ctx.Response.Header.Add(key, val)
+			},
+		},
+		"write": func(str string) {
+			ctx.WriteString(str)
+		},
+		"setStatus": func(status int) {
+			ctx.Response.SetStatusCode(status)
+		},
+		"status": func(status int) {
+			ctx.Response.SetStatusCode(status)
+		},
+		"send": func(contentType, body string, status ...int) {
+			ctx.Response.Header.Set("Content-Type", contentType)
+			if len(status) > 0 {
+				ctx.SetStatusCode(status[0])
+			}
+			ctx.Response.SetBodyString(body)
+		},
+		"redirect": func(url string, status ...int) {
+			code := http.StatusFound
+			if len(status) > 0 {
+				code = status[0]
+			}
+			ctx.Redirect(url, code)
+		},
+		"cookie": func(name, value string, maxAge ...int) {
+			c := fasthttp.AcquireCookie()
+			defer fasthttp.ReleaseCookie(c)
+			c.SetKey(name)
+			c.SetValue(value)
+			c.SetPath("/")
+			c.SetHTTPOnly(true)
+			if len(maxAge) > 0 {
+				c.SetMaxAge(maxAge[0])
+			}
+			ctx.Response.Header.SetCookie(c)
+		},
+		// The error helpers make the request fail with a valse error once
+		// the script returns, so the server's error handler renders it.
+		"error": func(status int, msg ...string) {
+			r.err = valse.NewHTTPMessage(status, msg...)
+		},
+		"noContent": func() {
+			ctx.SetStatusCode(http.StatusNoContent)
+		},
+		"badRequest":   fail(http.StatusBadRequest),
+		"unauthorized": fail(http.StatusUnauthorized),
+		"forbidden":    fail(http.StatusForbidden),
+		"notFound":     fail(http.StatusNotFound),
+	}
+}
@@ -0,0 +1,80 @@
+package lua
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func uploadedFile(t *testing.T, content string) *multipart.FileHeader {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fw, err := w.CreateFormFile("file", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(content))
+	w.Close()
+
+	form, err := multipart.NewReader(&body, w.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return form.File["file"][0]
+}
+
+func TestSaveFunc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fh := uploadedFile(t, "hello")
+
+	tests := []struct {
+		name    string
+		options LuaOptions
+		path    string
+		// saved is the file expected to be written, relative to dir.
+		saved string
+		// exposed is false when save is not available to scripts.
+		exposed bool
+	}{
+		{name: "trusted", options: LuaOptions{}, path: filepath.Join(dir, "trusted.txt"), saved: "trusted.txt", exposed: true},
+		{name: "sandbox", options: LuaOptions{Libraries: SandboxLibraries}},
+		{name: "upload dir", options: LuaOptions{UploadDir: dir}, path: "a.txt", saved: "a.txt", exposed: true},
+		{name: "upload dir sandbox", options: LuaOptions{UploadDir: dir, Libraries: SandboxLibraries}, path: "b.txt", saved: "b.txt", exposed: true},
+		{name: "absolute", options: LuaOptions{UploadDir: dir}, path: filepath.Join(dir, "c.txt"), exposed: true},
+		{name: "parent", options: LuaOptions{UploadDir: filepath.Join(dir, "sub")}, path: "../d.txt", exposed: true},
+		{name: "parent inside", options: LuaOptions{UploadDir: dir}, path: "x/../../e.txt", exposed: true},
+		{name: "empty", options: LuaOptions{UploadDir: dir}, path: "", exposed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			save := newSaveFunc(tt.options)
+			if (save != nil) != tt.exposed {
+				t.Fatalf("expected save exposed %v", tt.exposed)
+			}
+			if save == nil {
+				return
+			}
+			err := save(fh, tt.path)
+			if tt.saved == "" {
+				if err == nil {
+					t.Errorf("expected saving to %q to fail", tt.path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bs, err := ioutil.ReadFile(filepath.Join(dir, tt.saved)); err != nil || string(bs) != "hello" {
+				t.Errorf("expected %s to contain the upload, got %q, %v", tt.saved, bs, err)
+			}
+		})
+	}
+}
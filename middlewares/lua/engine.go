@@ -0,0 +1,75 @@
+package lua
+
+// Table is a table passed to scripts, such as req and res. Values may be
+// strings, numbers, booleans, slices, nested Tables and Go functions, which
+// the engine converts to their Lua counterparts.
+type Table map[string]interface{}
+
+// Engine creates the Lua states of the VM pool.
+//
+// Two engines are available: CLua runs the C Lua library through cgo and is
+// the default when cgo is enabled; GopherLua is a pure Go interpreter, used
+// for static binaries built with CGO_ENABLED=0.
+type Engine interface {
+	// NewState returns a state with the standard libraries, the valse
+	// extensions and the prelude loaded. The prelude's Router reports the
+	// routes and middlewares scripts register to factory.
+	NewState(options LuaOptions, factory RouterFactory) (State, error)
+}
+
+// State is a Lua interpreter created by an Engine. It is used by one
+// request at a time.
+type State interface {
+	// DoString runs a script; name is used in error messages.
+	DoString(name, source string) error
+	// SetGlobal sets the global name to value, converted like the values
+	// of a Table. A Go function whose last result is a non-nil error
+	// raises the error in Lua.
+	SetGlobal(name string, value interface{})
+	// Trigger calls the route or middleware id registered on router with
+	// the req and res tables, and returns the result of the call.
+	Trigger(id int, req, res Table) (bool, error)
+	// Memory returns the number of bytes the state uses, or 0 if the
+	// engine does not track it.
+	Memory() int
+	// Close releases the state.
+	Close()
+}
+
+// DefaultInstructionLimit is the number of instructions a route or
+// middleware may run on CLua when LuaOptions.InstructionLimit is 0.
+const DefaultInstructionLimit = 100000000
+
+// SandboxLibraries is a LuaOptions.Libraries profile for untrusted scripts:
+// it leaves out io, os, package and debug, so scripts cannot reach files,
+// processes or other modules.
+var SandboxLibraries = []string{"base", "table", "string", "math", "coroutine"}
+
+// unsafeBaseFunctions are removed from the base library when
+// LuaOptions.Libraries is set, as they read files.
+var unsafeBaseFunctions = []string{"dofile", "loadfile"}
+
+// preludes are the embedded scripts every state runs before the user's:
+// prelude.lua defines the Router, prelude2.lua the HTML builder.
+var preludes = []string{"prelude.lua", "prelude2.lua"}
+
+func loadPreludes(s State) error {
+	for _, name := range preludes {
+		if err := s.DoString(name, string(MustAsset(name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cEngine is set when the package is built with cgo.
+var cEngine Engine
+
+// DefaultEngine returns the engine used when LuaOptions.Engine is nil: CLua
+// when built with cgo, GopherLua otherwise.
+func DefaultEngine() Engine {
+	if cEngine != nil {
+		return cEngine
+	}
+	return GopherLua()
+}
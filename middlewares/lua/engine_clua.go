@@ -0,0 +1,229 @@
+//go:build cgo
+// +build cgo
+
+package lua
+
+import (
+	"fmt"
+
+	"github.com/aarzilli/golua/lua"
+	"github.com/stevedonovan/luar"
+)
+
+func init() {
+	cEngine = CLua()
+}
+
+type cLua struct {
+	factory func() *lua.State
+}
+
+// CLua returns the engine running the C Lua library through cgo.
+func CLua() Engine {
+	return CLuaWithFactory(nil)
+}
+
+// CLuaWithFactory returns a CLua engine creating its states with factory,
+// which must open the libraries scripts use. LuaOptions.Libraries is
+// ignored.
+func CLuaWithFactory(factory func() *lua.State) Engine {
+	return &cLua{factory: factory}
+}
+
+func (e *cLua) NewState(options LuaOptions, factory RouterFactory) (State, error) {
+	if options.Timeout != 0 {
+		return nil, fmt.Errorf("lua: CLua does not support Timeout, use InstructionLimit")
+	}
+	limit := options.InstructionLimit
+	if limit == 0 {
+		limit = DefaultInstructionLimit
+	}
+
+	var L *lua.State
+
+	switch {
+	case e.factory != nil:
+		L = e.factory()
+	case options.Libraries != nil:
+		L = luar.Init()
+		if err := restrictLibraries(L, options.Libraries); err != nil {
+			L.Close()
+			return nil, err
+		}
+	default:
+		L = luar.Init()
+		L.OpenLibs()
+	}
+
+	L.Register("__create_route", func(state *lua.State) int {
+		method := state.ToString(1)
+		route := state.ToString(2)
+		id := state.ToInteger(3)
+		group := state.ToInteger(4)
+
+		factory(method, route, id, group)
+		return 0
+	})
+
+	L.Register("__create_middleware", func(state *lua.State) int {
+		id := state.ToInteger(1)
+		name := state.ToString(2)
+		group := state.ToInteger(3)
+		factory("", name, id, group)
+		return 0
+	})
+
+	L.Register("__create_group", func(state *lua.State) int {
+		id := state.ToInteger(1)
+		prefix := state.ToString(2)
+		parent := state.ToInteger(3)
+		factory(groupMethod, prefix, id, parent)
+		return 0
+	})
+
+	registerExtensions(L)
+
+	s := &cState{L: L, limit: limit}
+	if err := loadPreludes(s); err != nil {
+		L.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// libraryGlobals are the globals set by each standard library of Lua 5.1,
+// where coroutines are part of the base library.
+var libraryGlobals = map[string][]string{
+	"base": {
+		"assert", "collectgarbage", "dofile", "error", "gcinfo", "getfenv",
+		"getmetatable", "ipairs", "load", "loadfile", "loadstring", "newproxy",
+		"next", "pairs", "pcall", "print", "rawequal", "rawget", "rawset",
+		"select", "setfenv", "setmetatable", "tonumber", "tostring", "type",
+		"unpack", "xpcall",
+	},
+	"coroutine": {"coroutine"},
+	"table":     {"table"},
+	"string":    {"string"},
+	"math":      {"math"},
+	"io":        {"io"},
+	"os":        {"os"},
+	"package":   {"package", "require", "module"},
+	"debug":     {"debug"},
+}
+
+// restrictLibraries removes the libraries not in names from a state
+// created by luar.Init, which opens all of them. They are removed from
+// package.loaded too, so require cannot return them.
+func restrictLibraries(L *lua.State, names []string) error {
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := libraryGlobals[name]; !ok {
+			return fmt.Errorf("lua: unknown library %q", name)
+		}
+		keep[name] = true
+	}
+
+	L.GetGlobal("package")
+	if L.IsTable(-1) {
+		L.GetField(-1, "loaded")
+		for name := range libraryGlobals {
+			if !keep[name] && name != "base" {
+				L.PushNil()
+				L.SetField(-2, name)
+			}
+		}
+		L.Pop(1)
+	}
+	L.Pop(1)
+
+	removed := unsafeBaseFunctions
+	for name, globals := range libraryGlobals {
+		if !keep[name] {
+			removed = append(removed[:len(removed):len(removed)], globals...)
+		}
+	}
+	for _, fn := range removed {
+		L.PushNil()
+		L.SetGlobal(fn)
+	}
+	return nil
+}
+
+type cState struct {
+	L     *lua.State
+	limit int
+}
+
+// DoString runs source. The C Lua library names the chunk after its source,
+// so name is only used for the error.
+func (s *cState) DoString(name, source string) error {
+	defer s.L.SetTop(0)
+	if err := s.L.DoString(source); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+func (s *cState) SetGlobal(name string, value interface{}) {
+	s.push(value)
+	s.L.SetGlobal(name)
+}
+
+func (s *cState) Trigger(id int, req, res Table) (bool, error) {
+	L := s.L
+	defer L.SetTop(0)
+	if s.limit > 0 {
+		// Setting the limit resets the instruction count.
+		L.SetExecutionLimit(s.limit)
+	}
+	L.GetGlobal("Router")
+	L.GetField(-1, "trigger")
+	L.GetGlobal("router")
+	L.PushInteger(int64(id))
+	s.push(req)
+	s.push(res)
+	if err := L.Call(4, 1); err != nil {
+		return false, err
+	}
+	return L.ToBoolean(-1), nil
+}
+
+// push pushes v, converting Tables to Lua tables so scripts can iterate and
+// extend them.
+func (s *cState) push(v interface{}) {
+	if fn, raises := errorResult(v); raises {
+		s.L.PushGoFunction(func(L *lua.State) int {
+			n := L.GetTop()
+			luar.GoToLua(L, fn)
+			L.Insert(1)
+			if err := L.Call(n, lua.LUA_MULTRET); err != nil {
+				L.RaiseError(err.Error())
+			}
+			top := L.GetTop()
+			if msg := L.ToString(top); msg != "" {
+				L.RaiseError(msg)
+			}
+			L.Pop(1)
+			return top - 1
+		})
+		return
+	}
+	t, ok := v.(Table)
+	if !ok {
+		luar.GoToLua(s.L, v)
+		return
+	}
+	s.L.NewTable()
+	for k, e := range t {
+		s.push(e)
+		s.L.SetField(-2, k)
+	}
+}
+
+func (s *cState) Memory() int {
+	return s.L.GC(lua.LUA_GCCOUNT, 0)*1024 + s.L.GC(lua.LUA_GCCOUNTB, 0)
+}
+
+func (s *cState) Close() {
+	s.L.Close()
+}
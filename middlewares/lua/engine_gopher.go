@@ -0,0 +1,292 @@
+package lua
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	luar "layeh.com/gopher-luar"
+)
+
+type gopherLua struct{}
+
+// GopherLua returns the engine running scripts on gopher-lua, a Lua 5.1
+// interpreter written in Go. It does not need cgo.
+func GopherLua() Engine {
+	return gopherLua{}
+}
+
+func (gopherLua) NewState(options LuaOptions, factory RouterFactory) (State, error) {
+	if options.InstructionLimit != 0 {
+		return nil, fmt.Errorf("lua: GopherLua does not support InstructionLimit, use Timeout")
+	}
+	if options.MemoryLimit != 0 {
+		return nil, fmt.Errorf("lua: GopherLua does not support MemoryLimit")
+	}
+	L := lua.NewState(lua.Options{SkipOpenLibs: options.Libraries != nil})
+	if options.Libraries != nil {
+		if err := openGopherLibraries(L, options.Libraries); err != nil {
+			L.Close()
+			return nil, err
+		}
+	}
+	s := &gopherState{L: L, timeout: options.Timeout}
+
+	L.SetGlobal("__create_route", L.NewFunction(func(L *lua.LState) int {
+		factory(L.CheckString(1), L.CheckString(2), L.CheckInt(3), L.OptInt(4, 0))
+		return 0
+	}))
+	L.SetGlobal("__create_middleware", L.NewFunction(func(L *lua.LState) int {
+		factory("", L.OptString(2, ""), L.CheckInt(1), L.OptInt(3, 0))
+		return 0
+	}))
+	L.SetGlobal("__create_group", L.NewFunction(func(L *lua.LState) int {
+		factory(groupMethod, L.CheckString(2), L.CheckInt(1), L.OptInt(3, 0))
+		return 0
+	}))
+
+	valse := L.NewTable()
+	valse.RawSetString("json", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"encode": jsonEncode,
+		"decode": jsonDecode,
+	}))
+	L.SetGlobal("valse", valse)
+
+	if err := loadPreludes(s); err != nil {
+		L.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+var gopherLibraries = map[string]lua.LGFunction{
+	"base":      lua.OpenBase,
+	"package":   lua.OpenPackage,
+	"table":     lua.OpenTable,
+	"string":    lua.OpenString,
+	"math":      lua.OpenMath,
+	"io":        lua.OpenIo,
+	"os":        lua.OpenOs,
+	"debug":     lua.OpenDebug,
+	"coroutine": lua.OpenCoroutine,
+}
+
+// openGopherLibraries opens the libraries in names. gopher-lua defines
+// require and module in the base library, they are removed too unless the
+// package library is opened.
+func openGopherLibraries(L *lua.LState, names []string) error {
+	removed := unsafeBaseFunctions
+	hasPackage := false
+	for _, name := range names {
+		open, ok := gopherLibraries[name]
+		if !ok {
+			return fmt.Errorf("lua: unknown library %q", name)
+		}
+		L.Push(L.NewFunction(open))
+		L.Push(lua.LString(name))
+		L.Call(1, 0)
+		hasPackage = hasPackage || name == "package"
+	}
+	if !hasPackage {
+		removed = append(removed[:len(removed):len(removed)], "require", "module")
+	}
+	for _, fn := range removed {
+		L.SetGlobal(fn, lua.LNil)
+	}
+	return nil
+}
+
+type gopherState struct {
+	L       *lua.LState
+	timeout time.Duration
+}
+
+func (s *gopherState) DoString(name, source string) error {
+	fn, err := s.L.Load(strings.NewReader(source), name)
+	if err != nil {
+		return err
+	}
+	s.L.Push(fn)
+	return s.L.PCall(0, 0, nil)
+}
+
+func (s *gopherState) SetGlobal(name string, value interface{}) {
+	s.L.SetGlobal(name, s.value(value))
+}
+
+func (s *gopherState) Trigger(id int, req, res Table) (bool, error) {
+	L := s.L
+	if s.timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		defer cancel()
+		L.SetContext(ctx)
+		defer L.RemoveContext()
+	}
+	trigger := L.GetField(L.GetGlobal("Router"), "trigger")
+	err := L.CallByParam(lua.P{Fn: trigger, NRet: 1, Protect: true},
+		L.GetGlobal("router"), lua.LNumber(id), s.value(req), s.value(res))
+	if err != nil {
+		return false, err
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+	return lua.LVAsBool(ret), nil
+}
+
+// Memory returns 0, gopher-lua values live on the Go heap.
+func (s *gopherState) Memory() int {
+	return 0
+}
+
+func (s *gopherState) Close() {
+	s.L.Close()
+}
+
+// value converts v to a Lua value. Tables and string slices become Lua
+// tables, also when a Go function returns them, so scripts can iterate them
+// with pairs and ipairs; other values are wrapped by gopher-luar.
+func (s *gopherState) value(v interface{}) lua.LValue {
+	switch v := v.(type) {
+	case Table:
+		t := s.L.NewTable()
+		for k, e := range v {
+			t.RawSetString(k, s.value(e))
+		}
+		return t
+	case []string:
+		t := s.L.NewTable()
+		for _, e := range v {
+			t.Append(lua.LString(e))
+		}
+		return t
+	}
+
+	if rv := reflect.ValueOf(v); rv.Kind() != reflect.Func || rv.IsNil() {
+		return luar.New(s.L, v)
+	}
+	v, raises := errorResult(v)
+	fn := luar.New(s.L, v)
+	return s.L.NewFunction(func(L *lua.LState) int {
+		L.Insert(fn, 1)
+		L.Call(L.GetTop()-1, lua.MultRet)
+		n := L.GetTop()
+		if raises {
+			if msg := L.ToString(n); msg != "" {
+				L.RaiseError("%s", msg)
+			}
+			L.Pop(1)
+			n--
+		}
+		for i := 1; i <= n; i++ {
+			if ud, ok := L.Get(i).(*lua.LUserData); ok {
+				switch ud.Value.(type) {
+				case Table, []string:
+					L.Replace(i, s.value(ud.Value))
+				}
+			}
+		}
+		return n
+	})
+}
+
+// jsonEncode implements valse.json.encode(value), returning the JSON text
+// and an error message or nil.
+func jsonEncode(L *lua.LState) int {
+	out := ""
+	v, err := fromLua(L.Get(1))
+	if err == nil {
+		var bs []byte
+		if bs, err = json.Marshal(v); err == nil {
+			out = string(bs)
+		}
+	}
+	L.Push(lua.LString(out))
+	if err != nil {
+		L.Push(lua.LString(err.Error()))
+	} else {
+		L.Push(lua.LNil)
+	}
+	return 2
+}
+
+// jsonDecode implements valse.json.decode(str), returning the decoded value
+// and an error message or nil.
+func jsonDecode(L *lua.LState) int {
+	var v interface{}
+	if err := json.Unmarshal([]byte(L.CheckString(1)), &v); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(toLua(L, v))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// fromLua converts v into a value encoding/json can marshal. Tables which
+// are sequences become slices, other tables maps.
+func fromLua(v lua.LValue) (interface{}, error) {
+	switch v := v.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(v), nil
+	case lua.LNumber:
+		return float64(v), nil
+	case lua.LString:
+		return string(v), nil
+	case *lua.LUserData:
+		return v.Value, nil
+	case *lua.LTable:
+		if n := v.MaxN(); n > 0 {
+			out := make([]interface{}, n)
+			for i := 1; i <= n; i++ {
+				e, err := fromLua(v.RawGetInt(i))
+				if err != nil {
+					return nil, err
+				}
+				out[i-1] = e
+			}
+			return out, nil
+		}
+		out := map[string]interface{}{}
+		var err error
+		v.ForEach(func(k, e lua.LValue) {
+			if err != nil {
+				return
+			}
+			out[k.String()], err = fromLua(e)
+		})
+		return out, err
+	}
+	return nil, fmt.Errorf("cannot encode a %s", v.Type())
+}
+
+// toLua converts a value decoded by encoding/json to a Lua value.
+func toLua(L *lua.LState, v interface{}) lua.LValue {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		t := L.NewTable()
+		for k, e := range v {
+			t.RawSetString(k, toLua(L, e))
+		}
+		return t
+	case []interface{}:
+		t := L.NewTable()
+		for _, e := range v {
+			t.Append(toLua(L, e))
+		}
+		return t
+	case string:
+		return lua.LString(v)
+	case float64:
+		return lua.LNumber(v)
+	case bool:
+		return lua.LBool(v)
+	}
+	return lua.LNil
+}
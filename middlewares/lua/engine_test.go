@@ -0,0 +1,99 @@
+package lua
+
+import (
+	"testing"
+	"time"
+)
+
+// engines returns the engines the package is built with.
+func engines() map[string]Engine {
+	es := map[string]Engine{"gopher": GopherLua()}
+	if cEngine != nil {
+		es["c"] = cEngine
+	}
+	return es
+}
+
+func noRoutes(string, string, int, int) {}
+
+func TestLibraries(t *testing.T) {
+	tests := []struct {
+		name      string
+		libraries []string
+		// script raises an error if the globals are not as expected.
+		script string
+	}{
+		{
+			name: "all",
+			script: `assert(io and os and debug and require and dofile and loadfile)
+				assert(string and table and math and coroutine)`,
+		},
+		{
+			name:      "sandbox",
+			libraries: SandboxLibraries,
+			script: `assert(io == nil and os == nil and debug == nil and package == nil)
+				assert(require == nil and module == nil and dofile == nil and loadfile == nil)
+				assert(string and table and math and coroutine and pcall)`,
+		},
+		{
+			name:      "package",
+			libraries: []string{"base", "package", "string"},
+			script: `assert(require and table == nil and io == nil)
+				assert(not pcall(require, "io") and not pcall(require, "os"))`,
+		},
+	}
+
+	for name, engine := range engines() {
+		for _, tt := range tests {
+			t.Run(name+" "+tt.name, func(t *testing.T) {
+				s, err := engine.NewState(LuaOptions{Libraries: tt.libraries}, noRoutes)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer s.Close()
+				if err := s.DoString("libraries.lua", tt.script); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+
+		t.Run(name+" unknown", func(t *testing.T) {
+			if _, err := engine.NewState(LuaOptions{Libraries: []string{"base", "net"}}, noRoutes); err == nil {
+				t.Error("expected an error for an unknown library")
+			}
+		})
+	}
+}
+
+func TestUnsupportedOptions(t *testing.T) {
+	tests := []struct {
+		engine  string
+		options LuaOptions
+		ok      bool
+	}{
+		{engine: "gopher", options: LuaOptions{Timeout: time.Second}, ok: true},
+		{engine: "gopher", options: LuaOptions{InstructionLimit: 1000}},
+		{engine: "gopher", options: LuaOptions{MemoryLimit: 1 << 20}},
+		{engine: "c", options: LuaOptions{InstructionLimit: 1000, MemoryLimit: 1 << 20}, ok: true},
+		{engine: "c", options: LuaOptions{InstructionLimit: -1}, ok: true},
+		{engine: "c", options: LuaOptions{Timeout: time.Second}},
+	}
+
+	es := engines()
+	for _, tt := range tests {
+		engine, ok := es[tt.engine]
+		if !ok {
+			continue
+		}
+		s, err := engine.NewState(tt.options, noRoutes)
+		if tt.ok && err != nil {
+			t.Errorf("%s: NewState(%+v) failed: %v", tt.engine, tt.options, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: expected NewState(%+v) to fail", tt.engine, tt.options)
+		}
+		if s != nil {
+			s.Close()
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package lua
+
+import (
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// errorMessage is raised for errors whose message is empty.
+const errorMessage = "error"
+
+// exportValue prepares a value given to LuaValse.Export. A struct, or a
+// pointer, with methods becomes a Table of its bound methods, named like
+// the method and with a lower-case first letter, so scripts call
+// cache.get(key). The values of a Table are prepared the same way.
+func exportValue(v interface{}) interface{} {
+	if t, ok := v.(Table); ok {
+		out := make(Table, len(t))
+		for k, e := range t {
+			out[k] = exportValue(e)
+		}
+		return out
+	}
+
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || rv.NumMethod() == 0 {
+		return v
+	}
+	if rv.Kind() != reflect.Ptr && rv.Kind() != reflect.Struct {
+		return v
+	}
+	methods := Table{}
+	for i := 0; i < rv.NumMethod(); i++ {
+		name := rv.Type().Method(i).Name
+		fn := rv.Method(i).Interface()
+		methods[name] = fn
+		methods[lowerFirst(name)] = fn
+	}
+	return methods
+}
+
+func lowerFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[n:]
+}
+
+// errorResult converts v, if it is a function whose last result is an
+// error, to a function returning the message of the error instead, "" for
+// nil and errorMessage for an error with an empty message. Engines raise
+// the message as a Lua error and drop the result. ok reports whether v was
+// converted.
+func errorResult(v interface{}) (fn interface{}, ok bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Func || rv.IsNil() {
+		return v, false
+	}
+	t := rv.Type()
+	if t.NumOut() == 0 || t.Out(t.NumOut()-1) != errorType {
+		return v, false
+	}
+
+	in := make([]reflect.Type, t.NumIn())
+	for i := range in {
+		in[i] = t.In(i)
+	}
+	out := make([]reflect.Type, t.NumOut())
+	for i := range out {
+		out[i] = t.Out(i)
+	}
+	out[len(out)-1] = reflect.TypeOf("")
+
+	ft := reflect.FuncOf(in, out, t.IsVariadic())
+	return reflect.MakeFunc(ft, func(args []reflect.Value) []reflect.Value {
+		var results []reflect.Value
+		if t.IsVariadic() {
+			results = rv.CallSlice(args)
+		} else {
+			results = rv.Call(args)
+		}
+		last := len(results) - 1
+		msg := ""
+		if err, _ := results[last].Interface().(error); err != nil {
+			msg = err.Error()
+			if msg == "" {
+				msg = errorMessage
+			}
+		}
+		results[last] = reflect.ValueOf(msg)
+		return results
+	}).Interface(), true
+}
@@ -0,0 +1,72 @@
+package lua
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testCache struct {
+	values map[string]string
+}
+
+func (c *testCache) Get(key string) (string, error) {
+	v, ok := c.values[key]
+	if !ok {
+		return "", errors.New("cache: no " + key)
+	}
+	return v, nil
+}
+
+func TestExport(t *testing.T) {
+	exports := map[string]interface{}{
+		"cache": &testCache{values: map[string]string{"a": "1"}},
+		"check": func(ok bool) error {
+			if !ok {
+				return errors.New("check failed")
+			}
+			return nil
+		},
+		"silent": func() error { return errors.New("") },
+		"plain":  func(a, b int) int { return a + b },
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		// err is part of the expected error, if the script fails.
+		err string
+	}{
+		{name: "method", script: `assert(cache.get("a") == "1" and cache.Get("a") == "1")`},
+		{name: "method error", script: `cache.get("b")`, err: "cache: no b"},
+		{name: "no error", script: `assert(check(true) == nil)`},
+		{name: "error", script: `check(false)`, err: "check failed"},
+		{name: "pcall", script: `local ok, err = pcall(check, false) assert(not ok and string.find(err, "check failed"))`},
+		{name: "empty error", script: `silent()`, err: errorMessage},
+		{name: "empty error pcall", script: `assert(not pcall(silent))`},
+		{name: "plain", script: `assert(plain(1, 2) == 3)`},
+	}
+
+	for name, engine := range engines() {
+		for _, tt := range tests {
+			t.Run(name+" "+tt.name, func(t *testing.T) {
+				s, err := engine.NewState(LuaOptions{}, noRoutes)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer s.Close()
+				for name, v := range exports {
+					s.SetGlobal(name, exportValue(v))
+				}
+
+				err = s.DoString("export.lua", tt.script)
+				if tt.err == "" && err != nil {
+					t.Fatal(err)
+				}
+				if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+					t.Errorf("expected an error containing %q, got %v", tt.err, err)
+				}
+			})
+		}
+	}
+}
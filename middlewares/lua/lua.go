@@ -1,74 +1,71 @@
-//go:generate go-bindata -pkg lua prelude.lua
+//go:generate go-bindata -pkg lua prelude.lua prelude2.lua test.lua
 package lua
 
 import (
+	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/Sirupsen/logrus"
-	"github.com/aarzilli/golua/lua"
-	"github.com/kildevaeld/valse"
-	"github.com/stevedonovan/luar"
+	"github.com/sirupsen/logrus"
+	"github.com/xwinie/valse"
 )
 
 type VM struct {
-	state *lua.State
+	state State
 	id    int
 }
 
-func createRequest(ctx *valse.Context) luar.Map {
-	return luar.Map{
-		"header": luar.Map{
-			"get": func(key string) string {
-				return string(ctx.Request.Header.Peek(key))
-			},
-		},
-		"url": func() luar.Map {
-			url := ctx.Request.URI()
-
-			return luar.Map{
-				"query": luar.Map{
-					"get": func(key string) string {
-						return string(url.QueryArgs().Peek(key))
-					},
-				},
-			}
-		},
-		"path":   string(ctx.Path()),
-		"method": string(ctx.Method()),
-		"body": func() string {
-			return string(ctx.PostBody())
-		},
-	}
-}
-
-func createResponse(ctx *valse.Context) luar.Map {
-	return luar.Map{
-		"header": luar.Map{
-			"get": func(key string) string {
-				return string(ctx.Response.Header.Peek(key))
-			},
-			"set": func(key, val string) {
-				ctx.Response.Header.Set(key, val)
-			},
-		},
-		"write": func(str string) {
-			ctx.WriteString(str)
-		},
-		"setStatus": func(status int) {
-			ctx.Response.SetStatusCode(status)
-		},
-	}
-}
-
 type LuaOptions struct {
 	Path        string
 	StopOnError bool
-	LuaFactory  func() *lua.State
 	WorkQueue   int
+	// Engine creates the Lua states.
+	// Optional. Default value CLua when built with cgo, GopherLua otherwise.
+	Engine Engine
+	// Libraries lists the standard libraries opened for scripts: "base",
+	// "package", "table", "string", "math", "io", "os", "debug" and
+	// "coroutine". When set, dofile and loadfile are removed from the base
+	// library. See SandboxLibraries.
+	// Optional. Default value nil, all libraries.
+	Libraries []string
+	// AcquireTimeout is how long a request waits for a free VM before it
+	// fails with 503 Service Unavailable.
+	// Optional. Default value 0, requests wait until a VM is free.
+	AcquireTimeout time.Duration
+	// Timeout limits how long a route or middleware runs. Only GopherLua
+	// supports it, CLua fails to create states when it is set.
+	// Optional. Default value 0, no limit.
+	Timeout time.Duration
+	// InstructionLimit limits the number of instructions a route or
+	// middleware runs; a negative value disables the limit. Only CLua
+	// supports it, GopherLua fails to create states when it is set.
+	// Optional. Default value DefaultInstructionLimit with CLua.
+	InstructionLimit int
+	// MemoryLimit is the number of bytes a VM may use. A VM using more
+	// after a request is replaced by a fresh one. Only CLua reports the
+	// memory of its states, GopherLua fails to create states when it is
+	// set.
+	// Optional. Default value 0, no limit.
+	MemoryLimit int
+	// UploadDir is the directory req.file(name).save(path) writes to. The
+	// path is relative to it and may not be absolute or contain "..".
+	// Without UploadDir save writes anywhere, and is not available to
+	// scripts run with Libraries set.
+	// Optional. Default value "".
+	UploadDir string
+	// Watch reloads the scripts when a file in Path changes. Requests in
+	// flight finish on the old scripts.
+	Watch bool
+	// WatchInterval is how often Path is checked for changes.
+	// Optional. Default value 1 second.
+	WatchInterval time.Duration
 }
 
 type File struct {
@@ -76,43 +73,42 @@ type File struct {
 	Content string
 }
 
-type RouterFactory func(method, path string, id int)
-
-func createLua(options LuaOptions, logger logrus.FieldLogger, files []File, factory RouterFactory) (*lua.State, error) {
-	var L *lua.State
+// RouterFactory is called for the routes, middlewares and groups a script
+// registers. For middlewares method is empty, and path is the name of a
+// named middleware or empty for one used on every request. For groups
+// method is "GROUP" and path is the prefix. group is the id of the group
+// the route, middleware or group was created on, or 0 for the router.
+type RouterFactory func(method, path string, id, group int)
 
-	if options.LuaFactory != nil {
-		L = options.LuaFactory()
-	} else {
-		L = luar.Init()
-		L.OpenLibs()
-	}
-
-	L.Register("__create_route", func(state *lua.State) int {
-		method := state.ToString(1)
-		route := state.ToString(2)
-		id := state.ToInteger(3)
+// groupMethod is the method RouterFactory is called with for groups.
+const groupMethod = "GROUP"
 
-		factory(method, route, id)
-		return 0
-	})
+// generationKey is the user value holding the generation serving a
+// request.
+const generationKey = "valse.lua.generation"
 
-	L.Register("__create_middleware", func(state *lua.State) int {
-		id := state.ToInteger(1)
-		factory("", "", id)
-		return 0
-	})
+func createLua(options LuaOptions, logger logrus.FieldLogger, files []File, exports map[string]interface{}, factory RouterFactory) (State, error) {
+	engine := options.Engine
+	if engine == nil {
+		engine = DefaultEngine()
+	}
 
-	registerExtensions(L)
+	L, err := engine.NewState(options, factory)
+	if err != nil {
+		return nil, err
+	}
 
-	L.DoString(string(MustAsset("prelude.lua")))
+	for name, value := range exports {
+		L.SetGlobal(name, value)
+	}
 
 	for _, file := range files {
 		//logger.Debugf("loading file: %s", file.Path)
 
-		err := L.DoString(file.Content)
+		err := L.DoString(file.Path, file.Content)
 		if err != nil {
 			if options.StopOnError {
+				L.Close()
 				return nil, err
 			}
 			logger.WithError(err).Errorf("could not load file: %s", file.Path)
@@ -123,6 +119,8 @@ func createLua(options LuaOptions, logger logrus.FieldLogger, files []File, fact
 	return L, nil
 }
 
+// getSortedFiles returns the scripts in path, leaving out the files whose
+// name starts with "_" and the *_test.lua files the luatest package runs.
 func getSortedFiles(path string) ([]string, error) {
 	files, err := ioutil.ReadDir(path)
 	if err != nil {
@@ -134,7 +132,7 @@ func getSortedFiles(path string) ([]string, error) {
 			continue
 		}
 		ext := filepath.Ext(file.Name())
-		if ext != ".lua" || strings.HasPrefix(file.Name(), "_") {
+		if ext != ".lua" || strings.HasPrefix(file.Name(), "_") || strings.HasSuffix(file.Name(), "_test.lua") {
 			continue
 		}
 
@@ -143,91 +141,553 @@ func getSortedFiles(path string) ([]string, error) {
 
 	sort.Strings(fileNames)
 
+	return fileNames, nil
+}
+
+// setLuaPath adds path to LUA_PATH, so scripts can require the modules in it.
+func setLuaPath(path string) {
+	pattern := path + "/?.lua"
 	luaPath := os.Getenv("LUA_PATH")
+	for _, p := range strings.Split(luaPath, ";") {
+		if p == pattern {
+			return
+		}
+	}
 	if luaPath != "" {
 		luaPath += ";"
 	}
+	os.Setenv("LUA_PATH", luaPath+pattern)
+}
+
+// snapshot describes the .lua files in path, so changes can be detected.
+func snapshot(path string) string {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".lua" {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", file.Name(), file.Size(), file.ModTime().UnixNano())
+	}
+	return b.String()
+}
 
-	luaPath += path + "/?.lua"
+// generation is a pool of VMs loaded from the same scripts, with the routes,
+// groups and middlewares the scripts registered.
+type generation struct {
+	pool   *pool
+	routes *routeTable
+	// keys maps the keys of the routes to the routes.
+	keys map[string]*luaRoute
+	// groups are the groups in registration order, which puts parents
+	// before their groups.
+	groups      []*luaGroup
+	middlewares []int
+	named       map[string]int
+	save        saveFunc
+	inflight    sync.WaitGroup
+}
 
-	os.Setenv("LUA_PATH", luaPath)
+// luaGroup is a group created with router:group.
+type luaGroup struct {
+	id     int
+	parent *luaGroup
+	prefix string
+	// path is the prefix joined to the ones of the parents, key tells
+	// apart the groups with the same path.
+	path        string
+	key         string
+	middlewares []int
+}
 
-	return fileNames, nil
+// group returns the group id, or nil for the router.
+func (g *generation) group(id int) *luaGroup {
+	for _, lg := range g.groups {
+		if lg.id == id {
+			return lg
+		}
+	}
+	return nil
 }
 
-type LuaValse struct {
-	o  LuaOptions
-	ch chan *lua.State
-	s  *valse.Server
-	id int
+// groupByKey returns the group with the given key, or nil.
+func (g *generation) groupByKey(key string) *luaGroup {
+	for _, lg := range g.groups {
+		if lg.key == key {
+			return lg
+		}
+	}
+	return nil
+}
+
+// run runs the middlewares ids in order. It reports whether they all let
+// the request through.
+func (g *generation) run(ctx *valse.Context, ids []int) (bool, error) {
+	for _, id := range ids {
+		ok, err := execute(ctx, g, id, nil)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// close waits for the requests in flight and closes the VMs.
+func (g *generation) close() {
+	g.inflight.Wait()
+	g.pool.close()
 }
 
-func (l LuaValse) loadFiles() []File {
-	logger := logrus.WithField("prefix", "middlewares:lua")
+// cleanPath returns p with a leading slash and without empty segments or a
+// trailing slash.
+func cleanPath(p string) string {
+	return path.Join("/", p)
+}
 
+type LuaValse struct {
+	o   LuaOptions
+	s   *valse.Server
+	log logrus.FieldLogger
+
+	mu      sync.RWMutex
+	gen     *generation
+	exports map[string]interface{}
+	done    chan struct{}
+	// mounted holds the keys of the routes added to the server by Open.
+	mounted map[string]bool
+}
+
+func (l *LuaValse) loadFiles() ([]File, error) {
 	files, err := getSortedFiles(l.o.Path)
 	if err != nil {
-		logger.Fatal(err)
+		return nil, err
 	}
 	var out []File
 	for _, file := range files {
 
 		bs, err := ioutil.ReadFile(file)
 		if err != nil {
-			logger.Fatal(err)
+			return nil, err
 		}
 		out = append(out, File{
 			Path:    file,
 			Content: string(bs),
 		})
 	}
-	return out
+	return out, nil
 }
 
-func (l *LuaValse) Open() error {
+// load creates a generation from the scripts in Path.
+func (l *LuaValse) load() (*generation, error) {
 	wn := l.o.WorkQueue
 	if wn == 0 {
 		wn = 5
 	}
 
-	logger := logrus.WithField("prefix", "middleware:lua")
-
-	files := l.loadFiles()
+	files, err := l.loadFiles()
+	if err != nil {
+		return nil, err
+	}
 
-	ch := make(chan *VM, wn+1)
-	logger.Infof("Registering %d lua vm's", wn)
-	for i := 0; i < wn; i++ {
-		lua, err := createLua(l.o, logger, files, func(method, path string, id int) {
-			if id <= l.id {
-				return
+	l.mu.RLock()
+	exports := make(map[string]interface{}, len(l.exports))
+	for name, value := range l.exports {
+		exports[name] = value
+	}
+	l.mu.RUnlock()
+
+	g := &generation{
+		pool:   newPool(wn, l.o.AcquireTimeout, l.o.MemoryLimit, l.log),
+		routes: newRouteTable(),
+		keys:   make(map[string]*luaRoute),
+		named:  make(map[string]int),
+		save:   newSaveFunc(l.o),
+	}
+	g.pool.create = func() (State, error) {
+		return createLua(l.o, l.log, files, exports, func(string, string, int, int) {})
+	}
+	var routeErr error
+	fail := func(err error) {
+		if routeErr == nil {
+			routeErr = err
+		}
+	}
+	// Every VM runs the same scripts, the routes are taken from the first.
+	register := func(method, p string, id, group int) {
+		parent := g.group(group)
+		if group != 0 && parent == nil {
+			fail(fmt.Errorf("lua group %d: not registered", group))
+			return
+		}
+		switch {
+		case method == groupMethod:
+			lg := &luaGroup{id: id, parent: parent, prefix: cleanPath(p)}
+			lg.path = lg.prefix
+			if parent != nil {
+				lg.path = path.Join(parent.path, lg.prefix)
 			}
-			l.id = id
-			if method == "" {
-				logger.Debugf("middleware '%d' added", id)
-				l.s.Use(middleware(id, ch))
-			} else {
-				logger.Debugf("path '%d' added: '%s'", id, path)
-				l.s.Route(method, path, route(id, ch))
+			n := 0
+			for _, other := range g.groups {
+				if other.path == lg.path {
+					n++
+				}
 			}
-		})
+			lg.key = fmt.Sprintf("%s#%d", lg.path, n)
+			l.log.Debugf("group '%d' added: '%s'", id, lg.path)
+			g.groups = append(g.groups, lg)
+		case method == "" && p != "":
+			l.log.Debugf("middleware '%d' added: '%s'", id, p)
+			if _, ok := g.named[p]; ok {
+				fail(fmt.Errorf("lua middleware %s: already registered", p))
+			}
+			g.named[p] = id
+		case method == "" && parent != nil:
+			l.log.Debugf("middleware '%d' added to group '%s'", id, parent.path)
+			parent.middlewares = append(parent.middlewares, id)
+		case method == "":
+			l.log.Debugf("middleware '%d' added", id)
+			g.middlewares = append(g.middlewares, id)
+		default:
+			local := cleanPath(p)
+			full := local
+			if parent != nil {
+				full = path.Join(parent.path, local)
+			}
+			l.log.Debugf("path '%d' added: '%s'", id, full)
+			r, err := g.routes.add(method, full, id)
+			if err != nil {
+				fail(err)
+				return
+			}
+			r.local, r.group = local, parent
+			g.keys[r.key()] = r
+		}
+	}
 
+	l.log.Infof("Registering %d lua vm's", wn)
+	for i := 0; i < wn; i++ {
+		factory := register
+		if i > 0 {
+			factory = func(string, string, int, int) {}
+		}
+		lua, err := createLua(l.o, l.log, files, exports, factory)
+		if err == nil {
+			err = routeErr
+		}
 		if err != nil {
+			g.close()
+			return nil, err
+		}
+
+		g.pool.add(&VM{lua, i})
+	}
+
+	return g, nil
+}
+
+func (l *LuaValse) Open() error {
+	setLuaPath(l.o.Path)
+
+	// The scripts are compared to the ones loaded, so a change made while
+	// they load is reloaded.
+	last := snapshot(l.o.Path)
+	g, err := l.load()
+	if err != nil {
+		return err
+	}
+	l.gen = g
+	l.s.Use(l.dispatch)
+	l.mount(g)
+
+	if l.o.Watch {
+		interval := l.o.WatchInterval
+		if interval == 0 {
+			interval = time.Second
+		}
+		l.done = make(chan struct{})
+		go l.watch(interval, last, l.done)
+	}
+
+	l.s.OnShutdown(func() error {
+		l.Close()
+		return nil
+	})
+
+	return nil
+}
+
+// mount adds the routes of g to the server. The routes of a group are added
+// to a valse.Group, mounted at the group's prefix on the server or on the
+// group of its parent, so the middlewares of the server and of the Go groups
+// they are mounted on apply. The handlers look the routes up by key in the
+// generation serving the request, so they follow reloads.
+func (l *LuaValse) mount(g *generation) {
+	l.mounted = make(map[string]bool, len(g.keys))
+	groups := make(map[*luaGroup]*valse.Group, len(g.groups))
+	for _, lg := range g.groups {
+		groups[lg] = valse.NewGroup().Use(l.groupMiddleware(lg.key))
+	}
+	for _, r := range g.routes.routes {
+		if r.group != nil {
+			groups[r.group].Route(r.method, r.local, l.route(r.key()))
+		} else {
+			l.s.Route(r.method, r.local, l.route(r.key()))
+		}
+		l.mounted[r.key()] = true
+	}
+	// A group copies the routes of the groups it mounts, so the groups are
+	// mounted after the ones they contain.
+	for i := len(g.groups) - 1; i >= 0; i-- {
+		lg := g.groups[i]
+		if lg.parent != nil {
+			groups[lg.parent].Mount(lg.prefix, groups[lg])
+		} else {
+			l.s.Mount(lg.prefix, groups[lg])
+		}
+	}
+}
+
+// dispatch is the middleware Open installs. It runs the middlewares scripts
+// add with router:use and records the generation serving the request. The
+// routes a reload adds are not known to the server's router, dispatch
+// serves them with the middlewares of their groups.
+func (l *LuaValse) dispatch(next valse.RequestHandler) valse.RequestHandler {
+	return func(ctx *valse.Context) error {
+		g := l.current()
+		if g == nil {
+			return next(ctx)
+		}
+		defer g.inflight.Done()
+		ctx.SetUserValue(generationKey, g)
+
+		ok, err := g.run(ctx, g.middlewares)
+		if err != nil || !ok {
 			return err
 		}
 
-		ch <- &VM{lua, i}
+		r, values := g.routes.lookup(string(ctx.Method()), string(ctx.Path()))
+		if r == nil || l.mounted[r.key()] {
+			return next(ctx)
+		}
+		for i, name := range r.params {
+			ctx.SetUserValue(name, values[i])
+		}
+		var chain []*luaGroup
+		for lg := r.group; lg != nil; lg = lg.parent {
+			chain = append([]*luaGroup{lg}, chain...)
+		}
+		for _, lg := range chain {
+			if ok, err := g.run(ctx, lg.middlewares); err != nil || !ok {
+				return err
+			}
+		}
+		_, err = execute(ctx, g, r.id, r.params)
+		return err
+	}
+}
+
+// generationOf returns the generation dispatch recorded for ctx, or nil.
+func generationOf(ctx *valse.Context) *generation {
+	g, _ := ctx.UserValue(generationKey).(*generation)
+	return g
+}
+
+// route returns the handler of the route with the given key.
+func (l *LuaValse) route(key string) valse.RequestHandler {
+	return func(ctx *valse.Context) error {
+		g := generationOf(ctx)
+		if g == nil {
+			return valse.NewHTTPMessage(http.StatusNotFound)
+		}
+		r, ok := g.keys[key]
+		if !ok {
+			// The route was removed by a reload.
+			return valse.NewHTTPMessage(http.StatusNotFound)
+		}
+		_, err := execute(ctx, g, r.id, r.params)
+		return err
+	}
+}
+
+// groupMiddleware returns the middleware running the middlewares of the
+// group with the given key.
+func (l *LuaValse) groupMiddleware(key string) valse.MiddlewareHandler {
+	return func(next valse.RequestHandler) valse.RequestHandler {
+		return func(ctx *valse.Context) error {
+			if g := generationOf(ctx); g != nil {
+				if lg := g.groupByKey(key); lg != nil {
+					if ok, err := g.run(ctx, lg.middlewares); err != nil || !ok {
+						return err
+					}
+				}
+			}
+			return next(ctx)
+		}
 	}
+}
 
+// current returns the current generation, counted as in flight, or nil if
+// the scripts are not loaded.
+func (l *LuaValse) current() *generation {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	g := l.gen
+	if g != nil {
+		g.inflight.Add(1)
+	}
+	return g
+}
+
+// Middleware returns the middleware scripts register as name with
+// router:middleware, to use with Server.Route or Group.Use. The name is
+// looked up on each request, so the middleware follows reloads; requests
+// fail if no script registers it.
+func (l *LuaValse) Middleware(name string) valse.MiddlewareHandler {
+	return func(next valse.RequestHandler) valse.RequestHandler {
+		return func(ctx *valse.Context) error {
+			g := l.current()
+			if g == nil {
+				return fmt.Errorf("lua middleware %s: scripts are not loaded", name)
+			}
+			defer g.inflight.Done()
+
+			id, found := g.named[name]
+			if !found {
+				return fmt.Errorf("lua middleware %s: not registered", name)
+			}
+			ok, err := execute(ctx, g, id, nil)
+			if err != nil || !ok {
+				return err
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// Reload loads the scripts in Path into a new pool of VMs and swaps it in.
+// The previous VMs are closed once their requests are done. If loading
+// fails the previous scripts stay in use.
+//
+// The server's router keeps the routes Open added: removed routes respond
+// 404 Not Found, and added routes are served by the middleware Open
+// installs, so the middlewares used after Open and the Go groups do not
+// run for them until the server is restarted.
+func (l *LuaValse) Reload() error {
+	g, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	old := l.gen
+	l.gen = g
+	l.mu.Unlock()
+
+	if old != nil {
+		go old.close()
+	}
 	return nil
 }
 
+// Export makes value available to scripts as the global name, converted
+// with luar. Functions, and the methods of a struct or pointer, whose last
+// result is a non-nil error raise it as a Lua error; the methods are
+// exported as a table, so scripts call them as cache.get(key). Exports are
+// set before the scripts run.
+//
+// Export values before calling Open. Once opened, every call to Export
+// reloads the scripts into a new pool of VMs so they all get the value.
+func (l *LuaValse) Export(name string, value interface{}) error {
+	l.mu.Lock()
+	if l.exports == nil {
+		l.exports = make(map[string]interface{})
+	}
+	l.exports[name] = exportValue(value)
+	open := l.gen != nil
+	l.mu.Unlock()
+
+	if open {
+		return l.Reload()
+	}
+	return nil
+}
+
+// Routes returns the routes registered by the loaded scripts, in
+// registration order, with the paths of their groups joined. Handler is
+// nil. Open adds the routes to the server, so Server.Routes lists them too,
+// but not the routes a reload adds.
+func (l *LuaValse) Routes() []valse.Route {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.gen == nil {
+		return nil
+	}
+	out := make([]valse.Route, len(l.gen.routes.routes))
+	for i, r := range l.gen.routes.routes {
+		out[i] = valse.Route{Method: r.method, Path: r.path}
+	}
+	return out
+}
+
+// Stats returns the state of the VM pool.
+func (l *LuaValse) Stats() PoolStats {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.gen == nil {
+		return PoolStats{}
+	}
+	return l.gen.pool.stats()
+}
+
+// watch reloads the scripts when the snapshot of Path differs from last.
+func (l *LuaValse) watch(interval time.Duration, last string, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			current := snapshot(l.o.Path)
+			if current == last {
+				continue
+			}
+			last = current
+			if err := l.Reload(); err != nil {
+				l.log.WithError(err).Error("could not reload lua scripts")
+				continue
+			}
+			l.log.Info("reloaded lua scripts")
+		}
+	}
+}
+
+// Close stops watching Path, waits for the requests in flight and closes
+// the VMs.
 func (l *LuaValse) Close() {
-	for c := range l.ch {
-		c.Close()
+	if l.done != nil {
+		close(l.done)
+		l.done = nil
 	}
-	close(l.ch)
 
+	l.mu.Lock()
+	g := l.gen
+	l.gen = nil
+	l.mu.Unlock()
+
+	if g != nil {
+		g.close()
+	}
 }
+
 func New(server *valse.Server, o LuaOptions) *LuaValse {
-	return &LuaValse{o, nil, server, 0}
+	return &LuaValse{
+		o:   o,
+		s:   server,
+		log: logrus.WithField("prefix", "middleware:lua"),
+	}
 }
@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 package lua
 
 import (
@@ -21,26 +24,8 @@ func registerExtensions(l *lua.State) {
 			},
 			"encode": func(state *lua.State) int {
 
-				var err error
 				var bs []byte
-				var oo interface{}
-				t := state.Type(1)
-				switch t {
-				case lua.LUA_TNIL, lua.LUA_TNONE:
-					oo = nil
-				case lua.LUA_TTABLE:
-					var o luar.Map
-					err = luar.LuaToGo(state, 1, &o)
-					oo = o
-				case lua.LUA_TNUMBER:
-					oo = state.ToNumber(1)
-				case lua.LUA_TSTRING:
-					oo = state.ToString(1)
-				case lua.LUA_TBOOLEAN:
-					oo = state.ToBoolean(1)
-				default:
-					err = errors.New("invalid type")
-				}
+				oo, err := toGoValue(state, 1)
 
 				out := ""
 				if err == nil {
@@ -62,3 +47,28 @@ func registerExtensions(l *lua.State) {
 	})
 
 }
+
+// toGoValue converts the value at idx into a value encoding/json can marshal.
+// Tables which are sequences become slices, other tables maps.
+func toGoValue(state *lua.State, idx int) (interface{}, error) {
+	switch state.Type(idx) {
+	case lua.LUA_TNIL, lua.LUA_TNONE:
+		return nil, nil
+	case lua.LUA_TTABLE:
+		if state.ObjLen(idx) > 0 {
+			var s luar.Slice
+			err := luar.LuaToGo(state, idx, &s)
+			return s, err
+		}
+		var o luar.Map
+		err := luar.LuaToGo(state, idx, &o)
+		return o, err
+	case lua.LUA_TNUMBER:
+		return state.ToNumber(idx), nil
+	case lua.LUA_TSTRING:
+		return state.ToString(idx), nil
+	case lua.LUA_TBOOLEAN:
+		return state.ToBoolean(idx), nil
+	}
+	return nil, errors.New("invalid type")
+}
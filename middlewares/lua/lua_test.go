@@ -0,0 +1,344 @@
+package lua
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"github.com/xwinie/valse"
+)
+
+func openScripts(t *testing.T, s *valse.Server, path string) *LuaValse {
+	l := New(s, LuaOptions{Path: path, Engine: GopherLua(), StopOnError: true, WorkQueue: 1})
+	if err := l.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestMiddleware(t *testing.T) {
+	s := valse.New()
+	l := openScripts(t, s, "testdata/scripts")
+	handler := func(c *valse.Context) error {
+		c.WriteString("go")
+		return nil
+	}
+	s.Get("/go", l.Middleware("auth"), handler)
+	s.Get("/missing", l.Middleware("missing"), handler)
+	h := s.GetHandler()
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		status int
+		body   string
+	}{
+		{name: "script route", path: "/hello/bob", status: 200, body: "hello bob"},
+		{name: "passes", path: "/go", token: "secret", status: 200, body: "go"},
+		{name: "stops", path: "/go", status: 401},
+		{name: "not registered", path: "/missing", status: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &fasthttp.RequestCtx{}
+			rc.Request.SetRequestURI(tt.path)
+			if tt.token != "" {
+				rc.Request.Header.Set("X-Token", tt.token)
+			}
+			h(rc)
+			if status := rc.Response.StatusCode(); status != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, status)
+			}
+			if tt.body != "" && string(rc.Response.Body()) != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, rc.Response.Body())
+			}
+		})
+	}
+
+	// Close waits for the requests in flight, so it hangs if one of them
+	// was not marked done.
+	closed := make(chan struct{})
+	go func() {
+		l.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return")
+	}
+}
+
+func TestGroups(t *testing.T) {
+	s := valse.New()
+	l := openScripts(t, s, "testdata/scripts")
+	defer l.Close()
+	// The middlewares used after Open run for the routes of the scripts,
+	// which are in the server's router.
+	s.Use(func(ctx *valse.Context, next valse.RequestHandler) error {
+		ctx.Response.Header.Add("X-Chain", "go")
+		return next(ctx)
+	})
+	h := s.GetHandler()
+
+	var routes []string
+	for _, r := range l.Routes() {
+		routes = append(routes, r.Method+" "+r.Path)
+	}
+	want := []string{"GET /hello/:name", "GET /api/users/:id", "GET /api/v2/files/*path"}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("expected routes %q, got %q", want, routes)
+	}
+
+	// The groups are mounted on the server, each adding its middleware.
+	middlewares := map[string]int{}
+	for _, r := range s.Routes() {
+		middlewares[r.Method+" "+r.Path] = r.Middlewares
+	}
+	for route, n := range map[string]int{"GET /hello/:name": 0, "GET /api/users/:id": 1, "GET /api/v2/files/*path": 2} {
+		if got, ok := middlewares[route]; !ok || got != n {
+			t.Errorf("expected server route %s with %d middlewares, got %v", route, n, middlewares)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		body  string
+		// chain lists the X-Chain headers set by the group middlewares.
+		chain string
+	}{
+		{name: "outside", path: "/hello/bob", body: "hello bob", chain: "go"},
+		{name: "group", path: "/api/users/7", body: "user 7", chain: "go,api"},
+		{name: "nested group", path: "/api/v2/files/a/b", token: "secret", body: "file /a/b", chain: "go,api,v2"},
+		{name: "nested group stops", path: "/api/v2/files/a", chain: "go,api,v2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &fasthttp.RequestCtx{}
+			rc.Request.SetRequestURI(tt.path)
+			if tt.token != "" {
+				rc.Request.Header.Set("X-Token", tt.token)
+			}
+			h(rc)
+			if body := string(rc.Response.Body()); body != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, body)
+			}
+			var chain []string
+			rc.Response.Header.VisitAll(func(k, v []byte) {
+				if string(k) == "X-Chain" {
+					chain = append(chain, string(v))
+				}
+			})
+			if got := strings.Join(chain, ","); got != tt.chain {
+				t.Errorf("expected middlewares %q, got %q", tt.chain, got)
+			}
+		})
+	}
+}
+
+// writeScript replaces the script of dir with source.
+func writeScript(t *testing.T, dir, source string) {
+	if err := ioutil.WriteFile(filepath.Join(dir, "app.lua"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// get requests path and returns the status and body of the response.
+func get(h fasthttp.RequestHandler, path string) (int, string) {
+	rc := &fasthttp.RequestCtx{}
+	rc.Request.SetRequestURI(path)
+	h(rc)
+	return rc.Response.StatusCode(), string(rc.Response.Body())
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, `
+router:get("/a", function(req, res) res.write("a1") end)
+router:get("/gone", function(req, res) res.write("gone") end)
+`)
+	s := valse.New()
+	l := openScripts(t, s, dir)
+	defer l.Close()
+	h := s.GetHandler()
+
+	if _, body := get(h, "/a"); body != "a1" {
+		t.Fatalf("expected body a1, got %q", body)
+	}
+
+	writeScript(t, dir, `
+router:get("/a", function(req, res) res.write("a2") end)
+router:group("/b", function(b)
+	b:use(function(req, res)
+		res.header.set("X-Group", "b")
+		return true
+	end)
+	b:get("/:id", function(req, res) res.write("b" .. req.params.id) end)
+end)
+`)
+	if err := l.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{path: "/a", status: 200, body: "a2"},
+		// Added by the reload, served by the middleware Open installs.
+		{path: "/b/7", status: 200, body: "b7"},
+		{path: "/gone", status: 404},
+	}
+	for _, tt := range tests {
+		status, body := get(h, tt.path)
+		if status != tt.status || tt.body != "" && body != tt.body {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.path, tt.status, tt.body, status, body)
+		}
+	}
+
+	var routes []string
+	for _, r := range l.Routes() {
+		routes = append(routes, r.Method+" "+r.Path)
+	}
+	if want := []string{"GET /a", "GET /b/:id"}; !reflect.DeepEqual(routes, want) {
+		t.Errorf("expected routes %q, got %q", want, routes)
+	}
+}
+
+func TestReloadSyntaxError(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, `router:get("/a", function(req, res) res.write("a1") end)`)
+	s := valse.New()
+	l := openScripts(t, s, dir)
+	defer l.Close()
+	h := s.GetHandler()
+
+	writeScript(t, dir, `router:get("/a", function(req, res) res.write("a2")`)
+	if err := l.Reload(); err == nil {
+		t.Fatal("expected Reload to fail")
+	}
+	if status, body := get(h, "/a"); status != 200 || body != "a1" {
+		t.Errorf("expected the previous scripts to serve 200 a1, got %d %q", status, body)
+	}
+	if stats := l.Stats(); stats.Size != 1 || stats.Idle != 1 {
+		t.Errorf("expected the previous VM to stay in the pool, got %+v", stats)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, `router:get("/a", function(req, res) res.write("a1") end)`)
+	s := valse.New()
+	l := New(s, LuaOptions{Path: dir, Engine: GopherLua(), StopOnError: true, WorkQueue: 1, Watch: true, WatchInterval: 10 * time.Millisecond})
+	if err := l.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	h := s.GetHandler()
+
+	writeScript(t, dir, `router:get("/a", function(req, res) res.write("a2, watched") end)`)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, body := get(h, "/a")
+		if body == "a2, watched" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the change to be reloaded, got %q", body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// closeEngine reports the states it created being closed.
+type closeEngine struct {
+	Engine
+	closed chan struct{}
+}
+
+func (e closeEngine) NewState(options LuaOptions, factory RouterFactory) (State, error) {
+	s, err := e.Engine.NewState(options, factory)
+	if err != nil {
+		return nil, err
+	}
+	return closeState{s, e.closed}, nil
+}
+
+type closeState struct {
+	State
+	closed chan struct{}
+}
+
+func (s closeState) Close() {
+	s.State.Close()
+	s.closed <- struct{}{}
+}
+
+func TestReloadInFlight(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, `router:get("/slow", function(req, res) block() res.write("old") end)`)
+	closed := make(chan struct{}, 2)
+	s := valse.New()
+	l := New(s, LuaOptions{Path: dir, Engine: closeEngine{GopherLua(), closed}, StopOnError: true, WorkQueue: 1})
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	l.Export("block", func() {
+		entered <- struct{}{}
+		<-release
+	})
+	if err := l.Open(); err != nil {
+		t.Fatal(err)
+	}
+	h := s.GetHandler()
+
+	type response struct {
+		status int
+		body   string
+	}
+	done := make(chan response, 1)
+	go func() {
+		status, body := get(h, "/slow")
+		done <- response{status, body}
+	}()
+	<-entered
+
+	writeScript(t, dir, `router:get("/slow", function(req, res) res.write("new") end)`)
+	if err := l.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, body := get(h, "/slow"); body != "new" {
+		t.Errorf("expected the new scripts to serve new, got %q", body)
+	}
+	select {
+	case <-closed:
+		t.Fatal("the previous VM was closed with a request in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if res := <-done; res.status != 200 || res.body != "old" {
+		t.Errorf("expected the request in flight to finish with 200 old, got %d %q", res.status, res.body)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("the previous VM was not closed")
+	}
+
+	l.Close()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not close the VM")
+	}
+}
@@ -0,0 +1,374 @@
+// Package luatest tests the scripts of the lua middleware without starting a
+// real server: requests go to a valse server over an in-memory listener.
+package luatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+	"github.com/xwinie/valse"
+	"github.com/xwinie/valse/middlewares/lua"
+)
+
+// idHeader correlates a response with the error its handler returned.
+const idHeader = "X-Luatest-Id"
+
+// Harness serves the scripts of a directory to synthetic requests.
+type Harness struct {
+	T testing.TB
+	// Server serves the requests. Go routes and middlewares, such as the
+	// ones returned by Lua.Middleware, may be added before the first
+	// request.
+	Server *valse.Server
+	Lua    *lua.LuaValse
+
+	options lua.LuaOptions
+	once    sync.Once
+	err     error
+	ln      *fasthttputil.InmemoryListener
+	client  *fasthttp.Client
+
+	mu   sync.Mutex
+	seq  int
+	errs map[string]error
+}
+
+// New returns a harness for the scripts in options.Path. The scripts are
+// loaded on the first request. Close releases the harness.
+func New(t testing.TB, options lua.LuaOptions) *Harness {
+	h := &Harness{
+		T:       t,
+		options: options,
+		errs:    make(map[string]error),
+	}
+	h.Server = valse.NewWithConfig(valse.Config{ErrorHandler: h.capture})
+	h.Lua = lua.New(h.Server, options)
+	return h
+}
+
+func (h *Harness) start() error {
+	h.once.Do(func() {
+		if h.err = h.Lua.Open(); h.err != nil {
+			return
+		}
+		h.ln = fasthttputil.NewInmemoryListener()
+		h.client = &fasthttp.Client{
+			Dial: func(addr string) (net.Conn, error) {
+				return h.ln.Dial()
+			},
+		}
+		go fasthttp.Serve(h.ln, h.Server.GetHandler())
+	})
+	return h.err
+}
+
+// capture records the error returned for a request, such as a
+// lua.ScriptError, so failed expectations can report it, and renders it
+// with valse.DefaultErrorHandler.
+func (h *Harness) capture(ctx *valse.Context, err error) {
+	h.mu.Lock()
+	h.errs[string(ctx.Request.Header.Peek(idHeader))] = err
+	h.mu.Unlock()
+	valse.DefaultErrorHandler(ctx, err)
+}
+
+// Close stops the listener and closes the VMs.
+func (h *Harness) Close() {
+	if h.ln != nil {
+		h.ln.Close()
+	}
+	h.Lua.Close()
+}
+
+// Request is a synthetic request, sent with Do.
+type Request struct {
+	h      *Harness
+	method string
+	uri    string
+	header [][2]string
+	body   []byte
+}
+
+// Request returns a request for method and uri, such as "/users/1?full=1".
+func (h *Harness) Request(method, uri string) *Request {
+	return &Request{h: h, method: method, uri: uri}
+}
+
+func (h *Harness) Get(uri string) *Request {
+	return h.Request(http.MethodGet, uri)
+}
+
+func (h *Harness) Post(uri string) *Request {
+	return h.Request(http.MethodPost, uri)
+}
+
+func (h *Harness) Put(uri string) *Request {
+	return h.Request(http.MethodPut, uri)
+}
+
+func (h *Harness) Delete(uri string) *Request {
+	return h.Request(http.MethodDelete, uri)
+}
+
+// Header sets a request header.
+func (r *Request) Header(key, value string) *Request {
+	r.header = append(r.header, [2]string{key, value})
+	return r
+}
+
+// Body sets the request body.
+func (r *Request) Body(body string) *Request {
+	r.body = []byte(body)
+	return r
+}
+
+// JSON sets the request body to v encoded as JSON.
+func (r *Request) JSON(v interface{}) *Request {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		r.h.T.Fatalf("%s %s: %v", r.method, r.uri, err)
+	}
+	r.body = bs
+	return r.Header("Content-Type", "application/json")
+}
+
+// Do sends the request. It fails the test if the request cannot be sent.
+func (r *Request) Do() *Response {
+	r.h.T.Helper()
+	res, err := r.do()
+	if err != nil {
+		r.h.T.Fatalf("%s %s: %v", r.method, r.uri, err)
+	}
+	return res
+}
+
+func (r *Request) do() (*Response, error) {
+	h := r.h
+	if err := h.start(); err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	h.seq++
+	id := strconv.Itoa(h.seq)
+	h.mu.Unlock()
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.Header.SetMethod(r.method)
+	req.SetRequestURI("http://luatest" + r.uri)
+	for _, kv := range r.header {
+		req.Header.Add(kv[0], kv[1])
+	}
+	req.Header.Set(idHeader, id)
+	req.SetBody(r.body)
+
+	if err := h.client.Do(req, resp); err != nil {
+		return nil, err
+	}
+
+	res := &Response{
+		t:      h.T,
+		name:   r.method + " " + r.uri,
+		Status: resp.StatusCode(),
+		Header: make(http.Header),
+		Body:   string(resp.Body()),
+	}
+	resp.Header.VisitAll(func(k, v []byte) {
+		res.Header.Add(string(k), string(v))
+	})
+
+	h.mu.Lock()
+	res.Err = h.errs[id]
+	delete(h.errs, id)
+	h.mu.Unlock()
+	return res, nil
+}
+
+// Response is the response to a Request. The Expect methods fail the test
+// when the response differs, reporting the error returned by the handler,
+// with its Lua stack trace for script errors.
+type Response struct {
+	t    testing.TB
+	name string
+
+	Status int
+	Header http.Header
+	Body   string
+	// Err is the error the handlers returned, nil if they succeeded.
+	Err error
+}
+
+func (r *Response) fail(format string, args ...interface{}) {
+	r.t.Helper()
+	msg := r.name + ": " + fmt.Sprintf(format, args...)
+	if r.Err != nil {
+		msg += "\n" + r.Err.Error()
+	}
+	r.t.Error(msg)
+}
+
+func (r *Response) ExpectStatus(status int) *Response {
+	r.t.Helper()
+	if r.Status != status {
+		r.fail("expected status %d, got %d", status, r.Status)
+	}
+	return r
+}
+
+func (r *Response) ExpectHeader(key, value string) *Response {
+	r.t.Helper()
+	if got := r.Header.Get(key); got != value {
+		r.fail("expected header %s %q, got %q", key, value, got)
+	}
+	return r
+}
+
+func (r *Response) ExpectBody(body string) *Response {
+	r.t.Helper()
+	if r.Body != body {
+		r.fail("expected body %q, got %q", body, r.Body)
+	}
+	return r
+}
+
+func (r *Response) ExpectBodyContains(s string) *Response {
+	r.t.Helper()
+	if !strings.Contains(r.Body, s) {
+		r.fail("expected body to contain %q, got %q", s, r.Body)
+	}
+	return r
+}
+
+// ExpectJSON checks that the body is the JSON encoding of v, ignoring
+// formatting and the order of object keys.
+func (r *Response) ExpectJSON(v interface{}) *Response {
+	r.t.Helper()
+	bs, err := json.Marshal(v)
+	if err != nil {
+		r.fail("%v", err)
+		return r
+	}
+	var want, got interface{}
+	json.Unmarshal(bs, &want)
+	if err := json.Unmarshal([]byte(r.Body), &got); err != nil {
+		r.fail("expected JSON body, got %q", r.Body)
+	} else if !reflect.DeepEqual(want, got) {
+		r.fail("expected JSON %s, got %s", bs, r.Body)
+	}
+	return r
+}
+
+// RunTests runs the *_test.lua files in the scripts directory, each test
+// registered with test(name, fn) as a subtest of t. Each file runs in its
+// own state, with the test library defining test and the assert_*
+// functions. Test files call
+// request(method, uri[, {headers = {...}, body = "..."}]), which sends a
+// request to the harness and returns a table with status, headers, body
+// and the error message of the handlers, if any.
+func (h *Harness) RunTests(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(h.options.Path, "*_test.lua"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.start(); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			h.runFile(t, file)
+		})
+	}
+}
+
+func (h *Harness) runFile(t *testing.T, file string) {
+	source, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := h.options.Engine
+	if engine == nil {
+		engine = lua.DefaultEngine()
+	}
+	state, err := engine.NewState(h.options, func(string, string, int, int) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer state.Close()
+
+	// The test library is only loaded here, not in the VMs serving
+	// requests.
+	if err := state.DoString("test.lua", string(lua.MustAsset("test.lua"))); err != nil {
+		t.Fatal(err)
+	}
+	state.SetGlobal("request", h.luaRequest)
+	state.SetGlobal("__luatest_report", func(name, failure string) {
+		t.Run(name, func(t *testing.T) {
+			if failure != "" {
+				t.Error(failure)
+			}
+		})
+	})
+	if err := state.DoString(file, string(source)); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.DoString("luatest", "__run_tests(__luatest_report)"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// luaRequest implements request for test files.
+func (h *Harness) luaRequest(method, uri string, opts ...map[string]interface{}) (lua.Table, error) {
+	r := h.Request(method, uri)
+	if len(opts) > 0 {
+		// Engines convert nested tables to maps with string or
+		// interface{} keys.
+		switch headers := opts[0]["headers"].(type) {
+		case map[string]interface{}:
+			for k, v := range headers {
+				r.Header(k, fmt.Sprint(v))
+			}
+		case map[interface{}]interface{}:
+			for k, v := range headers {
+				r.Header(fmt.Sprint(k), fmt.Sprint(v))
+			}
+		}
+		if body, ok := opts[0]["body"].(string); ok {
+			r.Body(body)
+		}
+	}
+	res, err := r.do()
+	if err != nil {
+		return nil, err
+	}
+
+	headers := lua.Table{}
+	for k := range res.Header {
+		headers[k] = res.Header.Get(k)
+	}
+	out := lua.Table{
+		"status":  res.Status,
+		"headers": headers,
+		"body":    res.Body,
+	}
+	if res.Err != nil {
+		out["error"] = res.Err.Error()
+	}
+	return out, nil
+}
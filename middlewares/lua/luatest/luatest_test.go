@@ -0,0 +1,104 @@
+package luatest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/xwinie/valse/middlewares/lua"
+)
+
+func newHarness(t *testing.T) *Harness {
+	return New(t, lua.LuaOptions{Path: "testdata/scripts", Engine: lua.GopherLua(), StopOnError: true, WorkQueue: 1})
+}
+
+func TestHarness(t *testing.T) {
+	h := newHarness(t)
+	defer h.Close()
+
+	tests := []struct {
+		name   string
+		req    *Request
+		status int
+		body   string
+		// err is part of the expected handler error.
+		err string
+	}{
+		{name: "route", req: h.Get("/hello/ann").Header("Authorization", "tok"), status: 200, body: `"hello":"ann"`},
+		{name: "json body", req: h.Post("/echo").JSON(map[string]int{"a": 1}), status: 201, body: `{"a":1}`},
+		{name: "script error", req: h.Get("/boom"), status: 500, err: "field"},
+		{name: "test library", req: h.Get("/globals"), status: 200, body: "nil,nil"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := tt.req.Do()
+			res.t = t
+			res.ExpectStatus(tt.status).ExpectBodyContains(tt.body)
+			if tt.err == "" && res.Err != nil {
+				t.Errorf("unexpected error %v", res.Err)
+			}
+			if tt.err != "" && (res.Err == nil || !strings.Contains(res.Err.Error(), tt.err)) {
+				t.Errorf("expected an error containing %q, got %v", tt.err, res.Err)
+			}
+		})
+	}
+}
+
+// recorder records the failures of a test.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Error(args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprint(args...))
+}
+
+func TestExpect(t *testing.T) {
+	h := newHarness(t)
+	defer h.Close()
+	res := h.Get("/hello/ann").Do()
+	boom := h.Get("/boom").Do()
+
+	tests := []struct {
+		name   string
+		res    *Response
+		expect func(r *Response)
+		// failure is part of the expected failure, if any.
+		failure string
+	}{
+		{name: "status", res: res, expect: func(r *Response) { r.ExpectStatus(200) }},
+		{name: "wrong status", res: res, expect: func(r *Response) { r.ExpectStatus(201) }, failure: "expected status 201, got 200"},
+		{name: "header", res: res, expect: func(r *Response) { r.ExpectHeader("X-Name", "ann") }},
+		{name: "wrong header", res: res, expect: func(r *Response) { r.ExpectHeader("X-Name", "bob") }, failure: `expected header X-Name "bob"`},
+		{name: "json", res: res, expect: func(r *Response) { r.ExpectJSON(map[string]string{"auth": "", "hello": "ann"}) }},
+		{name: "wrong json", res: res, expect: func(r *Response) { r.ExpectJSON(map[string]string{"hello": "bob"}) }, failure: "expected JSON"},
+		{name: "wrong body", res: res, expect: func(r *Response) { r.ExpectBody("ann") }, failure: "expected body"},
+		{name: "script error reported", res: boom, expect: func(r *Response) { r.ExpectStatus(200) }, failure: "field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{TB: t}
+			r := *tt.res
+			r.t = rec
+			tt.expect(&r)
+
+			switch {
+			case tt.failure == "" && len(rec.failures) > 0:
+				t.Errorf("unexpected failures %q", rec.failures)
+			case tt.failure != "" && (len(rec.failures) != 1 || !strings.Contains(rec.failures[0], tt.failure)):
+				t.Errorf("expected a failure containing %q, got %q", tt.failure, rec.failures)
+			}
+		})
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	h := newHarness(t)
+	defer h.Close()
+	h.RunTests(t)
+}
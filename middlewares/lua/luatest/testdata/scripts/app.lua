@@ -0,0 +1,18 @@
+router:get("/hello/:name", function(req, res)
+	res.header.set("X-Name", req.params.name)
+	res.json({ hello = req.params.name, auth = req.header.get("Authorization") })
+end)
+
+router:post("/echo", function(req, res)
+	res.send(req.header.get("Content-Type"), req.body(), 201)
+end)
+
+router:get("/boom", function(req, res)
+	local t = nil
+	return t.field
+end)
+
+-- The test library is only loaded for test files.
+router:get("/globals", function(req, res)
+	res.write(type(test) .. "," .. type(assert_equal))
+end)
@@ -0,0 +1,23 @@
+test("hello", function()
+	local res = request("GET", "/hello/bob", { headers = { Authorization = "x" } })
+	assert_equal(res.status, 200)
+	assert_equal(res.headers["X-Name"], "bob")
+	assert_match(res.body, '"auth":"x"')
+	assert_nil(res.error)
+end)
+
+test("echo", function()
+	local res = request("POST", "/echo", { headers = { ["Content-Type"] = "text/plain" }, body = "ping" })
+	assert_equal(res.status, 201)
+	assert_equal(res.body, "ping")
+end)
+
+test("script error", function()
+	local res = request("GET", "/boom")
+	assert_equal(res.status, 500)
+	assert_match(res.error, "field")
+end)
+
+test("not found", function()
+	assert_not_equal(request("GET", "/nope").status, 200)
+end)
@@ -0,0 +1,152 @@
+package lua
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xwinie/valse"
+)
+
+// PoolStats describes the VM pool of the loaded scripts. The counters start
+// at zero each time the scripts are loaded.
+type PoolStats struct {
+	// Size is the number of VMs in the pool.
+	Size int
+	// Busy is the number of VMs running a script.
+	Busy int
+	// Idle is the number of VMs waiting for a request.
+	Idle int
+	// Waiting is the number of requests waiting for a VM.
+	Waiting int
+	// Acquired is the number of times a VM was handed to a request.
+	Acquired uint64
+	// Timeouts is the number of requests which gave up waiting for a VM.
+	Timeouts uint64
+	// Recycled is the number of VMs replaced for using too much memory.
+	Recycled uint64
+	// WaitTime is the total time requests waited for a VM.
+	WaitTime time.Duration
+}
+
+// pool hands out the VMs of a generation, one request at a time.
+type pool struct {
+	// The counters come first to be 64-bit aligned for atomic access.
+	size     int64
+	busy     int64
+	waiting  int64
+	acquired uint64
+	timeouts uint64
+	recycled uint64
+	waitTime int64
+
+	ch          chan *VM
+	timeout     time.Duration
+	memoryLimit int
+	create      func() (State, error)
+	log         logrus.FieldLogger
+
+	// pending tracks the VMs being recycled.
+	pending sync.WaitGroup
+}
+
+func newPool(n int, timeout time.Duration, memoryLimit int, log logrus.FieldLogger) *pool {
+	return &pool{
+		ch:          make(chan *VM, n),
+		timeout:     timeout,
+		memoryLimit: memoryLimit,
+		log:         log,
+	}
+}
+
+func (p *pool) add(vm *VM) {
+	atomic.AddInt64(&p.size, 1)
+	p.ch <- vm
+}
+
+// acquire returns a free VM. It fails with 503 Service Unavailable if none
+// is released within the timeout of the pool.
+func (p *pool) acquire() (*VM, error) {
+	select {
+	case vm := <-p.ch:
+		return p.take(vm), nil
+	default:
+	}
+
+	atomic.AddInt64(&p.waiting, 1)
+	start := time.Now()
+	defer func() {
+		atomic.AddInt64(&p.waiting, -1)
+		atomic.AddInt64(&p.waitTime, int64(time.Since(start)))
+	}()
+
+	var timeout <-chan time.Time
+	if p.timeout > 0 {
+		t := time.NewTimer(p.timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case vm := <-p.ch:
+		return p.take(vm), nil
+	case <-timeout:
+		atomic.AddUint64(&p.timeouts, 1)
+		return nil, valse.NewHTTPMessage(http.StatusServiceUnavailable, "no lua vm available")
+	}
+}
+
+func (p *pool) take(vm *VM) *VM {
+	atomic.AddInt64(&p.busy, 1)
+	atomic.AddUint64(&p.acquired, 1)
+	return vm
+}
+
+// release returns vm to the pool. A VM using more memory than the limit of
+// the pool is closed and replaced by a new one in the background.
+func (p *pool) release(vm *VM) {
+	atomic.AddInt64(&p.busy, -1)
+	if p.memoryLimit <= 0 || vm.state.Memory() <= p.memoryLimit {
+		p.ch <- vm
+		return
+	}
+
+	atomic.AddUint64(&p.recycled, 1)
+	p.pending.Add(1)
+	go func() {
+		defer p.pending.Done()
+		vm.state.Close()
+		state, err := p.create()
+		if err != nil {
+			atomic.AddInt64(&p.size, -1)
+			p.log.WithError(err).Errorf("could not recreate lua vm %d", vm.id)
+			return
+		}
+		vm.state = state
+		p.ch <- vm
+	}()
+}
+
+// close closes the VMs. They must all have been released.
+func (p *pool) close() {
+	p.pending.Wait()
+	for n := atomic.LoadInt64(&p.size); n > 0; n-- {
+		vm := <-p.ch
+		vm.state.Close()
+	}
+}
+
+func (p *pool) stats() PoolStats {
+	return PoolStats{
+		Size:     int(atomic.LoadInt64(&p.size)),
+		Busy:     int(atomic.LoadInt64(&p.busy)),
+		Idle:     len(p.ch),
+		Waiting:  int(atomic.LoadInt64(&p.waiting)),
+		Acquired: atomic.LoadUint64(&p.acquired),
+		Timeouts: atomic.LoadUint64(&p.timeouts),
+		Recycled: atomic.LoadUint64(&p.recycled),
+		WaitTime: time.Duration(atomic.LoadInt64(&p.waitTime)),
+	}
+}
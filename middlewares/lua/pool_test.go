@@ -0,0 +1,218 @@
+package lua
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"github.com/xwinie/valse"
+)
+
+// blockingScripts serves /block, which waits until release is closed, and
+// /fast. entered receives a value when /block starts.
+func blockingScripts(t *testing.T, options LuaOptions) (l *LuaValse, h fasthttp.RequestHandler, entered, release chan struct{}) {
+	dir := t.TempDir()
+	writeScript(t, dir, `
+router:get("/block", function(req, res) block() res.write("blocked") end)
+router:get("/fast", function(req, res) res.write("fast") end)
+router:get("/loop", function(req, res) while true do end end)
+`)
+	options.Path = dir
+	options.StopOnError = true
+	if options.Engine == nil {
+		options.Engine = GopherLua()
+	}
+	s := valse.NewWithConfig(valse.Config{Logger: nopLogger{}})
+	l = New(s, options)
+	entered = make(chan struct{})
+	release = make(chan struct{})
+	l.Export("block", func() {
+		entered <- struct{}{}
+		<-release
+	})
+	if err := l.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return l, s.GetHandler(), entered, release
+}
+
+func TestAcquireTimeout(t *testing.T) {
+	l, h, entered, release := blockingScripts(t, LuaOptions{WorkQueue: 1, AcquireTimeout: 20 * time.Millisecond})
+	defer l.Close()
+
+	done := make(chan struct{})
+	go func() {
+		get(h, "/block")
+		close(done)
+	}()
+	<-entered
+
+	start := time.Now()
+	if status, _ := get(h, "/fast"); status != fasthttp.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", status)
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("expected the request to wait for the timeout, waited %v", waited)
+	}
+	if stats := l.Stats(); stats.Timeouts != 1 || stats.Waiting != 0 {
+		t.Errorf("expected 1 timeout and no request waiting, got %+v", stats)
+	}
+
+	close(release)
+	<-done
+	if status, body := get(h, "/fast"); status != 200 || body != "fast" {
+		t.Errorf("expected the released VM to serve 200 fast, got %d %q", status, body)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	l, h, _, _ := blockingScripts(t, LuaOptions{WorkQueue: 1, Timeout: 50 * time.Millisecond})
+	defer l.Close()
+
+	done := make(chan int, 1)
+	go func() {
+		status, _ := get(h, "/loop")
+		done <- status
+	}()
+	select {
+	case status := <-done:
+		if status != fasthttp.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d", status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the infinite loop was not stopped")
+	}
+
+	if status, body := get(h, "/fast"); status != 200 || body != "fast" {
+		t.Errorf("expected the VM to serve 200 fast after the timeout, got %d %q", status, body)
+	}
+}
+
+func TestStats(t *testing.T) {
+	l, h, entered, release := blockingScripts(t, LuaOptions{WorkQueue: 2})
+	defer l.Close()
+
+	if stats := l.Stats(); stats != (PoolStats{Size: 2, Idle: 2}) {
+		t.Errorf("expected 2 idle VMs, got %+v", stats)
+	}
+
+	done := make(chan struct{}, 3)
+	request := func(path string) {
+		go func() {
+			get(h, path)
+			done <- struct{}{}
+		}()
+	}
+	request("/block")
+	request("/block")
+	<-entered
+	<-entered
+	request("/fast")
+	waitFor(t, func() bool { return l.Stats().Waiting == 1 })
+
+	stats := l.Stats()
+	if stats.Size != 2 || stats.Busy != 2 || stats.Idle != 0 || stats.Waiting != 1 || stats.Acquired != 2 {
+		t.Errorf("expected 2 busy VMs and 1 request waiting, got %+v", stats)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	for i := 0; i < 3; i++ {
+		<-done
+	}
+
+	stats = l.Stats()
+	if stats.Size != 2 || stats.Busy != 0 || stats.Idle != 2 || stats.Waiting != 0 || stats.Acquired != 3 || stats.Timeouts != 0 {
+		t.Errorf("expected 2 idle VMs after 3 requests, got %+v", stats)
+	}
+	if stats.WaitTime < 10*time.Millisecond {
+		t.Errorf("expected the waiting request to be counted, got a wait time of %v", stats.WaitTime)
+	}
+}
+
+// memoryEngine creates GopherLua states reporting memory bytes, so
+// MemoryLimit can be tested without CLua.
+type memoryEngine struct {
+	memory  int
+	created *int32
+}
+
+func (e memoryEngine) NewState(options LuaOptions, factory RouterFactory) (State, error) {
+	options.MemoryLimit = 0
+	s, err := GopherLua().NewState(options, factory)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt32(e.created, 1)
+	return memoryState{s, e.memory}, nil
+}
+
+type memoryState struct {
+	State
+	memory int
+}
+
+func (s memoryState) Memory() int {
+	return s.memory
+}
+
+func TestMemoryLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		memory   int
+		recycled uint64
+	}{
+		{name: "below the limit", memory: 1 << 10},
+		{name: "over the limit", memory: 1 << 20, recycled: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created int32
+			engine := memoryEngine{memory: tt.memory, created: &created}
+			l, h, _, _ := blockingScripts(t, LuaOptions{WorkQueue: 1, Engine: engine, MemoryLimit: 1 << 16})
+			defer l.Close()
+
+			for i := 0; i < 3; i++ {
+				if status, body := get(h, "/fast"); status != 200 || body != "fast" {
+					t.Fatalf("expected 200 fast, got %d %q", status, body)
+				}
+			}
+			waitFor(t, func() bool { return l.Stats().Idle == 1 })
+
+			stats := l.Stats()
+			if stats.Recycled != tt.recycled || stats.Size != 1 {
+				t.Errorf("expected %d VMs recycled out of 1, got %+v", tt.recycled, stats)
+			}
+			if want := int32(1 + tt.recycled); atomic.LoadInt32(&created) != want {
+				t.Errorf("expected %d states created, got %d", want, created)
+			}
+		})
+	}
+}
+
+// waitFor waits until cond is true.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// nopLogger discards what it is given.
+type nopLogger struct{}
+
+func (nopLogger) Print(...interface{})          {}
+func (nopLogger) Printf(string, ...interface{}) {}
+func (nopLogger) Println(...interface{})        {}
+func (nopLogger) Fatal(...interface{})          {}
+func (nopLogger) Fatalf(string, ...interface{}) {}
+func (nopLogger) Fatalln(...interface{})        {}
+func (nopLogger) Panic(...interface{})          {}
+func (nopLogger) Panicf(string, ...interface{}) {}
+func (nopLogger) Panicln(...interface{})        {}
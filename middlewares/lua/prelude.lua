@@ -70,11 +70,17 @@ Router = class(function(a)
 	a.routes = {}
 end)
 
+-- Router:add stores fn under a new id and returns the id. Groups add their
+-- functions to the router they were created from, which triggers them.
+function Router:add(fn)
+	local root = self.router or self
+	root.id = root.id + 1
+	root.routes[root.id] = fn
+	return root.id
+end
 
 function Router:route(method, path, fn)
-	self.id = self.id + 1
-	self.routes[self.id] = fn
-	__create_route(method, path, self.id)
+	__create_route(method, path, self:add(fn), self.group_id)
 end
 
 function Router:get(path, fn)
@@ -98,14 +104,62 @@ function Router:head(path, fn)
 end
 
 function Router:use(fn)
-	self.id = self.id + 1
-	self.routes[self.id] = fn
-	__create_middleware(self.id)
+	__create_middleware(self:add(fn), "", self.group_id)
+end
+
+-- Router:middleware registers a middleware which only runs where Go uses
+-- it, see LuaValse.Middleware. Like with Router:use, fn returns true to
+-- pass the request on.
+function Router:middleware(name, fn)
+	__create_middleware(self:add(fn), name)
+end
+
+-- Router:group returns a group of routes sharing the path prefix and the
+-- middlewares added with group:use. If fn is given it is called with the
+-- group.
+--
+-- Each group is a valse.Group mounted on the server, or on its parent
+-- group, at prefix. Group middlewares run after the ones of router:use,
+-- outer groups first, and only for the routes of the group.
+function Router:group(prefix, fn)
+	local g = Group(self.router or self, prefix)
+	__create_group(g.group_id, prefix, self.group_id)
+	if fn then
+		fn(g)
+	end
+	return g
 end
 
 function Router:trigger(id, req, res)
+	local send = function(contentType, body, status)
+		if status then
+			res.send(contentType, body, status)
+		else
+			res.send(contentType, body)
+		end
+	end
+	res.json = function(value, status)
+		local body, err = valse.json.encode(value)
+		if err then
+			error(err, 2)
+		end
+		send("application/json; charset=utf-8", body, status)
+	end
+	-- res.html sends a string or renders a function with render_html,
+	-- which prelude2.lua defines.
+	res.html = function(body, status)
+		if type(body) == "function" then
+			body = render_html(body)
+		end
+		send("text/html; charset=utf-8", tostring(body), status)
+	end
 	return self.routes[id](req, res)
 end
 
+Group = class(Router, function(g, router, prefix)
+	g.router = router
+	g.prefix = prefix
+	g.group_id = router:add(false)
+end)
 
 router = Router()
\ No newline at end of file
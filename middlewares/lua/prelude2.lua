@@ -1,168 +1,219 @@
-local append_all
-append_all = function(buffer, ...)
-  for i = 1, select("#", ...) do
-    table.insert(buffer, (select(i, ...)))
+local escapes = {
+  ["&"] = "&amp;",
+  ["<"] = "&lt;",
+  [">"] = "&gt;",
+  ['"'] = "&quot;",
+  ["'"] = "&#39;"
+}
+escape_html = function(str)
+  return (string.gsub(tostring(str), "[&<>\"']", escapes))
+end
+local html_mt = {
+  __tostring = function(self)
+    return self.html
+  end,
+  __concat = function(a, b)
+    return tostring(a) .. tostring(b)
+  end
+}
+raw_html = function(str)
+  return setmetatable({
+    html = tostring(str)
+  }, html_mt)
+end
+local is_html
+is_html = function(v)
+  return getmetatable(v) == html_mt
+end
+local void_tags
+do
+  local _tbl_0 = { }
+  local _list_0 = {
+    "area",
+    "base",
+    "br",
+    "col",
+    "embed",
+    "hr",
+    "img",
+    "input",
+    "link",
+    "meta",
+    "param",
+    "source",
+    "track",
+    "wbr"
+  }
+  for _index_0 = 1, #_list_0 do
+    local t = _list_0[_index_0]
+    _tbl_0[t] = true
+  end
+  void_tags = _tbl_0
+end
+local elements
+do
+  local _tbl_0 = { }
+  local _list_0 = {
+    "a",
+    "abbr",
+    "address",
+    "article",
+    "aside",
+    "audio",
+    "b",
+    "blockquote",
+    "body",
+    "button",
+    "canvas",
+    "caption",
+    "code",
+    "colgroup",
+    "dd",
+    "del",
+    "details",
+    "div",
+    "dl",
+    "dt",
+    "em",
+    "fieldset",
+    "figure",
+    "footer",
+    "form",
+    "h1",
+    "h2",
+    "h3",
+    "h4",
+    "h5",
+    "h6",
+    "head",
+    "header",
+    "html",
+    "i",
+    "label",
+    "legend",
+    "li",
+    "main",
+    "nav",
+    "ol",
+    "optgroup",
+    "option",
+    "p",
+    "pre",
+    "section",
+    "select",
+    "small",
+    "span",
+    "strong",
+    "style",
+    "sub",
+    "summary",
+    "sup",
+    "table",
+    "tbody",
+    "td",
+    "textarea",
+    "tfoot",
+    "th",
+    "thead",
+    "time",
+    "title",
+    "tr",
+    "u",
+    "ul",
+    "video"
+  }
+  for _index_0 = 1, #_list_0 do
+    local t = _list_0[_index_0]
+    _tbl_0[t] = true
   end
+  elements = _tbl_0
 end
-local void_tags = {
-  img = true,
-  br = true,
-  input = true
+for t in pairs(void_tags) do
+  elements[t] = true
+end
+local raw_text_tags = {
+  style = true
 }
+local append_children
+append_children = function(buffer, v, raw_text)
+  if is_html(v) then
+    return table.insert(buffer, v.html)
+  elseif type(v) == "table" then
+    for _index_0 = 1, #v do
+      local child = v[_index_0]
+      append_children(buffer, child, raw_text)
+    end
+  elseif v ~= nil and v ~= false then
+    return table.insert(buffer, raw_text and raw_text(v) or escape_html(v))
+  end
+end
+local raw_text
+raw_text = function(tag_name)
+  return function(v)
+    local str = tostring(v)
+    if string.find(string.lower(str), "</" .. tag_name, 1, true) then
+      error("html: " .. tag_name .. " text may not contain </" .. tag_name)
+    end
+    return str
+  end
+end
 local build_tag
 build_tag = function(tag_name, opts)
   local buffer = {
     "<",
     tag_name
   }
-  if type(opts) == "table" then
-    for k, v in pairs(opts) do
-      if type(k) ~= "number" then
-        append_all(buffer, " ", k, '="', v, '"')
+  if type(opts) == "table" and not is_html(opts) then
+    local keys
+    do
+      local _accum_0 = { }
+      local _len_0 = 1
+      for k in pairs(opts) do
+        if type(k) == "string" then
+          _accum_0[_len_0] = k
+          _len_0 = _len_0 + 1
+        end
+      end
+      keys = _accum_0
+    end
+    table.sort(keys)
+    for _index_0 = 1, #keys do
+      local k = keys[_index_0]
+      if not string.find(k, "^[%w:-]+$") then
+        error("html: invalid attribute name " .. string.format("%q", k))
+      end
+      local v = opts[k]
+      if v == true then
+        table.insert(buffer, " " .. k)
+      elseif v ~= false then
+        table.insert(buffer, " " .. k .. '="' .. escape_html(v) .. '"')
       end
     end
   end
   if void_tags[tag_name] then
-    append_all(buffer, " />")
+    table.insert(buffer, " />")
   else
-    append_all(buffer, ">")
-    if type(opts) == "table" then
-      append_all(buffer, unpack(opts))
-    else
-      append_all(buffer, opts)
-    end
-    append_all(buffer, "</", tag_name, ">")
+    table.insert(buffer, ">")
+    append_children(buffer, opts, raw_text_tags[tag_name] and raw_text(tag_name))
+    table.insert(buffer, "</" .. tag_name .. ">")
   end
-  return table.concat(buffer)
+  return raw_html(table.concat(buffer))
 end
-local render_html
-render_html = function(fn)
-  setfenv(fn, setmetatable({ }, {
-    __index = function(self, tag_name)
-      return function(opts)
-        return build_tag(tag_name, opts)
-      end
+local env_mt = {
+  __index = function(self, name)
+    if not elements[name] and _G[name] ~= nil then
+      return _G[name]
     end
-  }))
-  return fn()
-end
-local json_response
-json_response = function(res)
-  return function(data)
-    local out = valse.json.encode(data)
-    res.header.set('Content-Type', 'application/json')
-    res.header.set('Content-Length', #out)
-    return res.write(out)
-  end
-end
-local html_response
-html_response = function(res)
-  return function(data)
-    if type(data == 'function') then
-      data = render_html(data)
+    return function(opts)
+      return build_tag(name, opts)
     end
-    res.header.set('Content-Type', 'text/html')
-    res.header.set('Content-Length', #out)
-    return res.write(out)
   end
+}
+render_html = function(fn)
+  setfenv(fn, setmetatable({
+    raw = raw_html
+  }, env_mt))
+  local buffer = { }
+  append_children(buffer, fn())
+  return table.concat(buffer)
 end
-local text_reponse
-text_reponse = function(res)
-  return function(data)
-    res.header.set('Content-Type', 'text/plain')
-    res.header.set('Content-Length', #out)
-    return res.write(out)
-  end
-end
-local response
-response = function(res)
-  return setmetatable(res, {
-    __index = function(res, key)
-      local _exp_0 = key
-      if "json" == _exp_0 then
-        return json_response(res)
-      elseif "html" == _exp_0 then
-        return html_response(res)
-      else
-        return res[key]
-      end
-    end
-  })
-end
-local Router
-do
-  local _class_0
-  local _base_0 = {
-    _routes = { },
-    _id = 0,
-    route = function(self, method, path, fn)
-      self._id = self._id + 1
-      self._routes[self._id] = fn
-    end,
-    get = function(self, path, fn)
-      return self:route("GET", path, fn)
-    end,
-    trigger = function(self, id, req, res)
-      return self._routes[id](req, response(res))
-    end
-  }
-  _base_0.__index = _base_0
-  _class_0 = setmetatable({
-    __init = function() end,
-    __base = _base_0,
-    __name = "Router"
-  }, {
-    __index = _base_0,
-    __call = function(cls, ...)
-      local _self_0 = setmetatable({}, _base_0)
-      cls.__init(_self_0, ...)
-      return _self_0
-    end
-  })
-  _base_0.__class = _class_0
-  Router = _class_0
-end
-function print_r ( t )  
-    local print_r_cache={}
-    local function sub_print_r(t,indent)
-        if (print_r_cache[tostring(t)]) then
-            print(indent.."*"..tostring(t))
-        else
-            print_r_cache[tostring(t)]=true
-            if (type(t)=="table") then
-                for pos,val in pairs(t) do
-                    if (type(val)=="table") then
-                        print(indent.."["..pos.."] => "..tostring(t).." {")
-                        sub_print_r(val,indent..string.rep(" ",string.len(pos)+8))
-                        print(indent..string.rep(" ",string.len(pos)+6).."}")
-                    elseif (type(val)=="string") then
-                        print(indent.."["..pos..'] => "'..val..'"')
-                    else
-                        print(indent.."["..pos.."] => "..tostring(val))
-                    end
-                end
-            else
-                print(indent..tostring(t))
-            end
-        end
-    end
-    if (type(t)=="table") then
-        print(tostring(t).." {")
-        sub_print_r(t,"  ")
-        print("}")
-    else
-        sub_print_r(t,"  ")
-    end
-    print()
-end
-
-
-router = Router()
-router:get("/test", function()
-  return print("hello")
-end)
-
-router:get("/test", function()
-  return print("hello")
-end)
-return print_r(router._routes)
@@ -0,0 +1,57 @@
+package lua
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   string
+		html string
+		// err is part of the expected error, if rendering fails.
+		err string
+	}{
+		{name: "text", fn: `p "a < b"`, html: "<p>a &lt; b</p>"},
+		{name: "attributes", fn: `a { href = "/?a=1&b=2", title = '"x"', "link" }`, html: `<a href="/?a=1&amp;b=2" title="&quot;x&quot;">link</a>`},
+		{name: "boolean attributes", fn: `input { type = "checkbox", checked = true, disabled = false }`, html: `<input checked type="checkbox" />`},
+		{name: "data attribute", fn: `div { ["data-id"] = 1, ["xml:lang"] = "en" }`, html: `<div data-id="1" xml:lang="en"></div>`},
+		{name: "nested", fn: `ul { li "a", li { b "b" } }`, html: "<ul><li>a</li><li><b>b</b></li></ul>"},
+		{name: "raw", fn: `div { raw "<hr>" }`, html: "<div><hr></div>"},
+		{name: "style", fn: `style "p > a { content: \"&\" }"`, html: `<style>p > a { content: "&" }</style>`},
+		{name: "style table", fn: `style { "a > b {}", "i {}" }`, html: "<style>a > b {}i {}</style>"},
+		{name: "style closed", fn: `style "</STYLE><script>x()</script>"`, err: "may not contain </style"},
+		{name: "attribute name space", fn: `div { ["a b"] = 1 }`, err: "invalid attribute name"},
+		{name: "attribute name quote", fn: `div { ['x="y" onclick'] = 1 }`, err: "invalid attribute name"},
+		{name: "attribute name tag", fn: `div { ["a><script"] = 1 }`, err: "invalid attribute name"},
+	}
+
+	for name, engine := range engines() {
+		for _, tt := range tests {
+			t.Run(name+" "+tt.name, func(t *testing.T) {
+				s, err := engine.NewState(LuaOptions{}, noRoutes)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer s.Close()
+
+				var html string
+				s.SetGlobal("result", func(v string) { html = v })
+				err = s.DoString("html.lua", "result(render_html(function() return "+tt.fn+" end))")
+				if tt.err != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.err) {
+						t.Errorf("expected an error containing %q, got %v", tt.err, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if html != tt.html {
+					t.Errorf("expected %s, got %s", tt.html, html)
+				}
+			})
+		}
+	}
+}
@@ -1,55 +1,49 @@
 package lua
 
 import (
-	"github.com/kildevaeld/valse"
-	"github.com/stevedonovan/luar"
+	"github.com/xwinie/valse"
 )
 
-func execute(ctx *valse.Context, ch chan *VM, id int, middleware bool) bool {
-	req := createRequest(ctx)
-	res := createResponse(ctx)
+// ScriptError is returned for requests whose script raised an error.
+type ScriptError struct {
+	Err error
+}
+
+func (e *ScriptError) Error() string {
+	return "lua: " + e.Err.Error()
+}
+
+// Unwrap returns the error raised by the script.
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
 
-	vm := <-ch
-	defer func() {
-		ch <- vm
-	}()
+// execute calls the route or middleware id with the request and response
+// bridges. It reports whether a middleware let the request through.
+func execute(ctx *valse.Context, g *generation, id int, params []string) (bool, error) {
+	p := g.pool
+	r := &result{}
+	req := createRequest(ctx, params, g.save)
+	res := createResponse(ctx, r)
+
+	vm, err := p.acquire()
+	if err != nil {
+		return false, err
+	}
+	defer p.release(vm)
 
 	/*logrus.WithFields(logrus.Fields{
 		"path": string(ctx.Path()),
 		"vm":   vm.id,
 	}).Debugf("execute lua script")*/
 
-	state := vm.state
-	state.GetGlobal("router")
-	state.GetGlobal("Router")
-	state.GetField(-1, "trigger")
-	state.PushValue(-3)
-	luar.GoToLua(state, id)
-	luar.GoToLua(state, req)
-	luar.GoToLua(state, res)
-	state.Call(4, 1)
-
-	return vm.state.ToBoolean(0)
-}
-
-func route(id int, ch chan *VM) valse.RequestHandler {
-
-	return func(ctx *valse.Context) error {
-
-		execute(ctx, ch, id, false)
-
-		return nil
+	ok, err := vm.state.Trigger(id, req, res)
+	if err != nil {
+		return false, &ScriptError{err}
 	}
-}
-
-func middleware(id int, ch chan *VM) valse.MiddlewareHandler {
-	return func(next valse.RequestHandler) valse.RequestHandler {
-		return func(ctx *valse.Context) error {
-
-			if execute(ctx, ch, id, true) {
-				return next(ctx)
-			}
-			return nil
-		}
+	if r.err != nil {
+		return false, r.err
 	}
+
+	return ok, nil
 }
@@ -0,0 +1,145 @@
+package lua
+
+import (
+	"fmt"
+	"strings"
+)
+
+// luaRoute is a route registered by a script.
+type luaRoute struct {
+	id     int
+	method string
+	path   string
+	params []string
+	// local is the path relative to group, the group of the route or nil
+	// for the router.
+	local string
+	group *luaGroup
+}
+
+// key identifies the route across generations.
+func (r *luaRoute) key() string {
+	return r.method + " " + r.path
+}
+
+// routeTable matches request paths against the routes registered by the
+// scripts of one generation, with the same ":name" and "*name" syntax as
+// the server's router. Static segments take precedence over parameters,
+// which take precedence over catch-all parameters.
+type routeTable struct {
+	trees map[string]*node
+	// routes are the routes in registration order.
+	routes []*luaRoute
+}
+
+type node struct {
+	static   map[string]*node
+	param    *node
+	name     string
+	catchAll *luaRoute
+	route    *luaRoute
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{trees: make(map[string]*node)}
+}
+
+// splitPath returns the segments of path, leaving out empty ones so
+// "/a//b/" is split like "/a/b".
+func splitPath(path string) []string {
+	var segs []string
+	for _, seg := range strings.Split(path, "/") {
+		if seg != "" {
+			segs = append(segs, seg)
+		}
+	}
+	return segs
+}
+
+// add adds the route id, which it returns.
+func (t *routeTable) add(method, path string, id int) (*luaRoute, error) {
+	n, ok := t.trees[method]
+	if !ok {
+		n = &node{}
+		t.trees[method] = n
+	}
+	r := &luaRoute{id: id, method: method, path: path, params: paramNames(path)}
+
+	segs := splitPath(path)
+	for i, seg := range segs {
+		if (seg[0] == ':' || seg[0] == '*') && len(seg) == 1 {
+			return nil, fmt.Errorf("lua route %s %s: parameter must have a name", method, path)
+		}
+		switch {
+		case seg[0] == ':':
+			if n.param == nil {
+				n.param = &node{name: seg[1:]}
+			} else if n.param.name != seg[1:] {
+				return nil, fmt.Errorf("lua route %s %s: parameter ':%s' conflicts with ':%s'", method, path, seg[1:], n.param.name)
+			}
+			n = n.param
+		case seg[0] == '*':
+			if i != len(segs)-1 {
+				return nil, fmt.Errorf("lua route %s %s: catch-all parameter must be last", method, path)
+			}
+			if n.catchAll != nil {
+				return nil, fmt.Errorf("lua route %s %s: already registered as %s", method, path, n.catchAll.path)
+			}
+			n.catchAll = r
+			t.routes = append(t.routes, r)
+			return r, nil
+		default:
+			if n.static == nil {
+				n.static = make(map[string]*node)
+			}
+			child, ok := n.static[seg]
+			if !ok {
+				child = &node{}
+				n.static[seg] = child
+			}
+			n = child
+		}
+	}
+	if n.route != nil {
+		return nil, fmt.Errorf("lua route %s %s: already registered as %s", method, path, n.route.path)
+	}
+	n.route = r
+	t.routes = append(t.routes, r)
+	return r, nil
+}
+
+// lookup returns the route matching path and its parameter values, in the
+// order of luaRoute.params.
+func (t *routeTable) lookup(method, path string) (*luaRoute, []string) {
+	n, ok := t.trees[method]
+	if !ok {
+		return nil, nil
+	}
+	return n.match(splitPath(path), nil)
+}
+
+func (n *node) match(segs []string, values []string) (*luaRoute, []string) {
+	if len(segs) == 0 {
+		if n.route != nil {
+			return n.route, values
+		}
+		if n.catchAll != nil {
+			return n.catchAll, append(values, "/")
+		}
+		return nil, nil
+	}
+	if child, ok := n.static[segs[0]]; ok {
+		if r, v := child.match(segs[1:], values); r != nil {
+			return r, v
+		}
+	}
+	if n.param != nil {
+		if r, v := n.param.match(segs[1:], append(values, segs[0])); r != nil {
+			return r, v
+		}
+	}
+	if n.catchAll != nil {
+		return n.catchAll, append(values, "/"+strings.Join(segs, "/"))
+	}
+	return nil, nil
+}
@@ -0,0 +1,96 @@
+package lua
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRouteTable(t *testing.T) {
+	routes := []struct {
+		method, path string
+	}{
+		{"GET", "/"},
+		{"GET", "/users"},
+		{"GET", "/users/me"},
+		{"GET", "/users/:id"},
+		{"GET", "/users/:id/posts/:post"},
+		{"GET", "/files/*path"},
+		{"GET", "/a//b/"},
+		{"POST", "/users"},
+	}
+	table := newRouteTable()
+	for id, r := range routes {
+		if _, err := table.add(r.method, r.path, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		method, path string
+		// id is the index of the expected route in routes, or -1.
+		id     int
+		values []string
+	}{
+		{"GET", "/", 0, nil},
+		{"GET", "/users", 1, nil},
+		{"GET", "/users/", 1, nil},
+		{"GET", "/users/me", 2, nil},
+		{"GET", "/users/7", 3, []string{"7"}},
+		{"GET", "/users/7/posts/9", 4, []string{"7", "9"}},
+		{"GET", "/users/7/posts", -1, nil},
+		{"GET", "/files", 5, []string{"/"}},
+		{"GET", "/files/a/b.txt", 5, []string{"/a/b.txt"}},
+		{"GET", "/a/b", 6, nil},
+		{"GET", "/a//b", 6, nil},
+		{"POST", "/users", 7, nil},
+		{"POST", "/users/7", -1, nil},
+		{"DELETE", "/users", -1, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			r, values := table.lookup(tt.method, tt.path)
+			if tt.id < 0 {
+				if r != nil {
+					t.Errorf("expected no route, got %s", r.path)
+				}
+				return
+			}
+			if r == nil || r.id != tt.id {
+				t.Fatalf("expected route %s, got %v", routes[tt.id].path, r)
+			}
+			if !reflect.DeepEqual(values, tt.values) {
+				t.Errorf("expected values %q, got %q", tt.values, values)
+			}
+		})
+	}
+}
+
+func TestRouteTableConflicts(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+	}{
+		{name: "duplicate", paths: []string{"/users", "/users/"}},
+		{name: "parameter names", paths: []string{"/users/:id", "/users/:name"}},
+		{name: "catch-all not last", paths: []string{"/files/*path/x"}},
+		{name: "duplicate catch-all", paths: []string{"/files/*path", "/files/*rest"}},
+		{name: "unnamed parameter", paths: []string{"/users/:"}},
+		{name: "unnamed catch-all", paths: []string{"/files/*"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := newRouteTable()
+			var err error
+			for id, path := range tt.paths {
+				if _, err = table.add("GET", path, id); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				t.Errorf("expected adding %q to fail", tt.paths)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+-- test.lua is the test library of *_test.lua files: they register tests
+-- with test(name, fn) and check results with the assert functions below.
+-- The luatest package loads it in the states running the test files, it is
+-- not part of the prelude.
+
+local tests = {}
+
+function test(name, fn)
+	table.insert(tests, { name = name, fn = fn })
+end
+
+function __run_tests(report)
+	local traceback = debug and debug.traceback or tostring
+	for _, t in ipairs(tests) do
+		local ok, err = xpcall(t.fn, traceback)
+		report(t.name, ok and "" or tostring(err))
+	end
+	tests = {}
+end
+
+-- The traceback locates the failed assertion, as engines count error
+-- levels differently.
+local function fail(msg, reason)
+	if msg then
+		reason = msg .. ": " .. reason
+	end
+	error(reason, 0)
+end
+
+function assert_equal(actual, expected, msg)
+	if actual ~= expected then
+		fail(msg, string.format("expected %s, got %s", tostring(expected), tostring(actual)))
+	end
+end
+
+function assert_not_equal(actual, unexpected, msg)
+	if actual == unexpected then
+		fail(msg, string.format("expected a value other than %s", tostring(unexpected)))
+	end
+end
+
+function assert_true(value, msg)
+	if not value then
+		fail(msg, "expected a true value, got " .. tostring(value))
+	end
+end
+
+function assert_nil(value, msg)
+	if value ~= nil then
+		fail(msg, "expected nil, got " .. tostring(value))
+	end
+end
+
+function assert_match(str, pattern, msg)
+	if type(str) ~= "string" or not string.find(str, pattern) then
+		fail(msg, string.format("%q does not match %q", tostring(str), pattern))
+	end
+end
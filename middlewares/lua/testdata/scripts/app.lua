@@ -0,0 +1,31 @@
+router:get("/hello/:name", function(req, res)
+	res.write("hello " .. req.params.name)
+end)
+
+router:middleware("auth", function(req, res)
+	if req.header.get("X-Token") ~= "secret" then
+		res.unauthorized("missing token")
+		return false
+	end
+	res.header.set("X-User", "alice")
+	return true
+end)
+
+router:group("/api", function(api)
+	api:use(function(req, res)
+		res.header.add("X-Chain", "api")
+		return true
+	end)
+	api:get("/users/:id", function(req, res)
+		res.write("user " .. req.params.id)
+	end)
+	api:group("/v2/", function(v2)
+		v2:use(function(req, res)
+			res.header.add("X-Chain", "v2")
+			return req.header.get("X-Token") == "secret"
+		end)
+		v2:get("/files/*path", function(req, res)
+			res.write("file " .. req.params.path)
+		end)
+	end)
+end)
@@ -0,0 +1,99 @@
+// Package recovery recovers from panics in the handlers of a valse server.
+package recovery
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/xwinie/valse"
+)
+
+type (
+	// RecoverConfig defines the config for Recover middleware.
+	RecoverConfig struct {
+		// Size of the stack to be captured.
+		// Optional. Default value 4KB.
+		StackSize int `json:"stack_size"`
+
+		// DisableStackAll disables formatting stack traces of all other goroutines
+		// into the captured stack.
+		// Optional. Default value false.
+		DisableStackAll bool `json:"disable_stack_all"`
+
+		// DisablePrintStack disables logging the stack trace.
+		// Optional. Default value false.
+		DisablePrintStack bool `json:"disable_print_stack"`
+
+		// Logger is used to log the panic and its stack.
+		// Optional. Default value the logger of the request, see Context.Log.
+		Logger valse.Logger
+
+		// OnPanic is called with the recovered value and the captured stack,
+		// e.g. to send an alert.
+		// Optional.
+		OnPanic func(c *valse.Context, err error, stack []byte)
+	}
+)
+
+var (
+	// DefaultRecoverConfig is the default Recover middleware config.
+	DefaultRecoverConfig = RecoverConfig{
+		StackSize:         4 << 10, // 4 KB
+		DisableStackAll:   false,
+		DisablePrintStack: false,
+	}
+)
+
+// Recover returns a middleware which recovers from panics anywhere in the
+// chain and returns them as errors, so they are answered by the server's
+// ErrorHandler. Panics are logged to logger, or to the logger of the request
+// if it is nil.
+func Recover(logger valse.Logger) valse.MiddlewareHandler {
+	c := DefaultRecoverConfig
+	c.Logger = logger
+	return RecoverWithConfig(c)
+}
+
+// RecoverWithConfig returns a Recover middleware with config.
+// See: `Recover()`.
+func RecoverWithConfig(config RecoverConfig) valse.MiddlewareHandler {
+	// Defaults
+	if config.StackSize == 0 {
+		config.StackSize = DefaultRecoverConfig.StackSize
+	}
+
+	return func(next valse.RequestHandler) valse.RequestHandler {
+		return func(c *valse.Context) (err error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+
+				switch v := r.(type) {
+				case error:
+					err = fmt.Errorf("panic recovered: %w", v)
+				default:
+					err = fmt.Errorf("panic recovered: %v", v)
+				}
+
+				stack := make([]byte, config.StackSize)
+				stack = stack[:runtime.Stack(stack, !config.DisableStackAll)]
+
+				if !config.DisablePrintStack {
+					var logger valse.Logger = c.Log()
+					if config.Logger != nil {
+						logger = config.Logger
+					}
+					logger.Printf("[PANIC RECOVER] %s %s: %v\n%s", c.Method(), c.Path(), r, stack)
+				}
+
+				if config.OnPanic != nil {
+					config.OnPanic(c, err, stack)
+				}
+			}()
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package recovery
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"github.com/xwinie/valse"
+)
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(c *valse.Context) error
+		status  int
+		logged  string
+	}{
+		{
+			name:    "no panic",
+			handler: func(c *valse.Context) error { return c.JSON("ok") },
+			status:  fasthttp.StatusOK,
+		},
+		{
+			name:    "panic value",
+			handler: func(c *valse.Context) error { panic("boom") },
+			status:  fasthttp.StatusInternalServerError,
+			logged:  "[PANIC RECOVER] GET /panic: boom",
+		},
+		{
+			name:    "panic error",
+			handler: func(c *valse.Context) error { panic(errors.New("broken")) },
+			status:  fasthttp.StatusInternalServerError,
+			logged:  "[PANIC RECOVER] GET /panic: broken",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var recovered error
+			config := DefaultRecoverConfig
+			config.Logger = log.New(&buf, "", 0)
+			config.OnPanic = func(c *valse.Context, err error, stack []byte) {
+				recovered = err
+			}
+
+			s := valse.New()
+			s.Use(RecoverWithConfig(config))
+			s.Get("/panic", tt.handler)
+
+			rc := &fasthttp.RequestCtx{}
+			rc.Request.SetRequestURI("/panic")
+			s.GetHandler()(rc)
+
+			if got := rc.Response.StatusCode(); got != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, got)
+			}
+			if tt.logged == "" {
+				if buf.Len() != 0 || recovered != nil {
+					t.Errorf("expected nothing recovered, got %v: %q", recovered, buf.String())
+				}
+				return
+			}
+			if !strings.HasPrefix(buf.String(), tt.logged) {
+				t.Errorf("expected log %q, got %q", tt.logged, buf.String())
+			}
+			if recovered == nil || !strings.HasPrefix(recovered.Error(), "panic recovered: ") {
+				t.Errorf("expected OnPanic to get the recovered error, got %v", recovered)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package requestid
+
+import (
+	"crypto/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid"
+	"github.com/xwinie/valse"
+)
+
+type (
+	// RequestIDConfig defines the config for RequestID middleware.
+	RequestIDConfig struct {
+		// Generator defines a function to generate an ID.
+		// Optional. Default value UUIDv4.
+		Generator func() string
+
+		// Header is the request and response header carrying the ID.
+		// Optional. Default value "X-Request-Id".
+		Header string `json:"header"`
+	}
+)
+
+var (
+	// DefaultRequestIDConfig is the default RequestID middleware config.
+	DefaultRequestIDConfig = RequestIDConfig{
+		Generator: UUIDv4,
+		Header:    valse.HeaderXRequestID,
+	}
+)
+
+// UUIDv4 returns a random UUID.
+func UUIDv4() string {
+	return uuid.New().String()
+}
+
+// ULID returns a lexicographically sortable ULID.
+func ULID() string {
+	return ulid.MustNew(ulid.Timestamp(time.Now()), rand.Reader).String()
+}
+
+// RequestID returns a middleware which makes sure every request has an ID.
+// The ID sent by the client is kept if it is made of at most 128 letters,
+// digits, '.', '_' and '-', otherwise a new one is generated. It is
+// stored on the context, where Context.RequestID() and Context.Log() read it,
+// and echoed in the response header.
+func RequestID() valse.MiddlewareHandler {
+	return RequestIDWithConfig(DefaultRequestIDConfig)
+}
+
+// RequestIDWithConfig returns a RequestID middleware with config.
+// See: `RequestID()`.
+func RequestIDWithConfig(config RequestIDConfig) valse.MiddlewareHandler {
+	// Defaults
+	if config.Generator == nil {
+		config.Generator = DefaultRequestIDConfig.Generator
+	}
+	if config.Header == "" {
+		config.Header = DefaultRequestIDConfig.Header
+	}
+
+	return func(next valse.RequestHandler) valse.RequestHandler {
+		return func(c *valse.Context) error {
+			id := string(c.Request.Header.Peek(config.Header))
+			if !validID(id) {
+				id = config.Generator()
+			}
+
+			c.SetRequestID(id)
+			c.Response.Header.Set(config.Header, id)
+
+			return next(c)
+		}
+	}
+}
+
+// maxIDLength is the maximum length of an ID sent by the client.
+const maxIDLength = 128
+
+// validID reports whether id, sent by the client, is safe to log and echo.
+func validID(id string) bool {
+	if id == "" || len(id) > maxIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch b := id[i]; {
+		case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9',
+			b == '.', b == '_', b == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package requestid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"github.com/xwinie/valse"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "missing", header: "", want: "generated"},
+		{name: "uuid", header: "3f2b8c1e-7a4d-4f0e-9c6b-1d2e3f4a5b6c", want: "3f2b8c1e-7a4d-4f0e-9c6b-1d2e3f4a5b6c"},
+		{name: "dotted", header: "svc.a_1-2", want: "svc.a_1-2"},
+		{name: "max length", header: strings.Repeat("a", 128), want: strings.Repeat("a", 128)},
+		{name: "too long", header: strings.Repeat("a", 129), want: "generated"},
+		{name: "space", header: "a b", want: "generated"},
+		{name: "control", header: "a\x1bb", want: "generated"},
+		{name: "newline", header: "a\nINFO forged", want: "generated"},
+		{name: "unicode", header: "idé", want: "generated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			s := valse.New()
+			s.Use(RequestIDWithConfig(RequestIDConfig{
+				Generator: func() string { return "generated" },
+			}))
+			s.Get("/", func(c *valse.Context) error {
+				got = c.RequestID()
+				return nil
+			})
+
+			rc := &fasthttp.RequestCtx{}
+			rc.Request.SetRequestURI("/")
+			if tt.header != "" {
+				rc.Request.Header.Set(valse.HeaderXRequestID, tt.header)
+			}
+			s.GetHandler()(rc)
+
+			if got != tt.want {
+				t.Errorf("expected request id %q, got %q", tt.want, got)
+			}
+			if h := string(rc.Response.Header.Peek(valse.HeaderXRequestID)); h != tt.want {
+				t.Errorf("expected response header %q, got %q", tt.want, h)
+			}
+		})
+	}
+}
+
+func TestRequestIDHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		use  valse.MiddlewareHandler
+		// header is the header the client sends "abc" in.
+		header string
+		want   string
+	}{
+		{name: "custom header", use: RequestIDWithConfig(RequestIDConfig{Header: "X-Trace-Id"}), header: "X-Trace-Id", want: "abc"},
+		{name: "default header with custom header", use: RequestIDWithConfig(RequestIDConfig{Header: "X-Trace-Id", Generator: func() string { return "generated" }}), header: valse.HeaderXRequestID, want: "generated"},
+		{name: "without middleware", header: valse.HeaderXRequestID, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			s := valse.New()
+			if tt.use != nil {
+				s.Use(tt.use)
+			}
+			s.Get("/", func(c *valse.Context) error {
+				got = c.RequestID()
+				return nil
+			})
+
+			rc := &fasthttp.RequestCtx{}
+			rc.Request.SetRequestURI("/")
+			rc.Request.Header.Set(tt.header, "abc")
+			s.GetHandler()(rc)
+
+			if got != tt.want {
+				t.Errorf("expected request id %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
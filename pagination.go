@@ -1,14 +1,118 @@
 package valse
 
 import (
+	"crypto/rand"
+	"fmt"
 	"math"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// Default pagination settings.
+const (
+	DefaultPageParam    = "p"
+	DefaultPerPageParam = "per_page"
+	DefaultPerPage      = 10
+	DefaultMaxPerPage   = 100
+	DefaultCursorParam  = "cursor"
+	DefaultCursorMaxAge = 24 * time.Hour
+)
+
+// HeaderXTotalCount is the header Paginate sets to the total number of items.
+const HeaderXTotalCount = "X-Total-Count"
+
+// PaginationConfig configures Context.Paginate and Context.Cursor.
+type PaginationConfig struct {
+	// Query parameter holding the page number.
+	//
+	// DefaultPageParam is used if not set.
+	PageParam string
+
+	// Query parameter holding the number of items per page.
+	//
+	// DefaultPerPageParam is used if not set.
+	PerPageParam string
+
+	// Number of items per page if the request does not set it.
+	//
+	// DefaultPerPage is used if not set.
+	PerPage int
+
+	// Upper limit for the number of items per page a request may ask for.
+	//
+	// DefaultMaxPerPage is used if not set.
+	MaxPerPage int
+
+	// Query parameter holding the cursor token.
+	//
+	// DefaultCursorParam is used if not set.
+	CursorParam string
+
+	// Key used to sign cursor tokens.
+	//
+	// A random key is generated if not set, so tokens do not survive a
+	// restart and are not accepted by other instances.
+	CursorSecret []byte
+
+	// How long cursor tokens are accepted after they were issued.
+	//
+	// DefaultCursorMaxAge is used if not set.
+	CursorMaxAge time.Duration
+}
+
+// defaultPagination is used by contexts without a server.
+var (
+	defaultPagination     PaginationConfig
+	defaultPaginationOnce sync.Once
+)
+
+// paginationConfig returns the pagination config of the server, or the
+// default one if the context has no server.
+func (c *Context) paginationConfig() *PaginationConfig {
+	if c.s != nil {
+		return &c.s.pagination
+	}
+	defaultPaginationOnce.Do(defaultPagination.setDefaults)
+	return &defaultPagination
+}
+
+func (c *PaginationConfig) setDefaults() {
+	if c.PageParam == "" {
+		c.PageParam = DefaultPageParam
+	}
+	if c.PerPageParam == "" {
+		c.PerPageParam = DefaultPerPageParam
+	}
+	if c.PerPage <= 0 {
+		c.PerPage = DefaultPerPage
+	}
+	if c.MaxPerPage <= 0 {
+		c.MaxPerPage = DefaultMaxPerPage
+	}
+	if c.PerPage > c.MaxPerPage {
+		c.PerPage = c.MaxPerPage
+	}
+	if c.CursorParam == "" {
+		c.CursorParam = DefaultCursorParam
+	}
+	if c.CursorMaxAge <= 0 {
+		c.CursorMaxAge = DefaultCursorMaxAge
+	}
+	if len(c.CursorSecret) == 0 {
+		c.CursorSecret = make([]byte, 32)
+		if _, err := rand.Read(c.CursorSecret); err != nil {
+			panic(err)
+		}
+	}
+}
+
 // Paginator within the state of a http request.
 type Paginator struct {
 	url         string
+	key         string
 	PerPageNums int
 	MaxPages    int
 
@@ -47,7 +151,7 @@ func (p *Paginator) Page() int {
 		return p.page
 	}
 	link, _ := url.ParseRequestURI(p.url)
-	p.page, _ = strconv.Atoi(link.Query().Get("p"))
+	p.page, _ = strconv.Atoi(link.Query().Get(p.pageKey()))
 	if p.page > p.PageNums() {
 		p.page = p.PageNums()
 	}
@@ -93,14 +197,21 @@ func (p *Paginator) PageLink(page int) string {
 	link, _ := url.ParseRequestURI(p.url)
 	values := link.Query()
 	if page == 1 {
-		values.Del("p")
+		values.Del(p.pageKey())
 	} else {
-		values.Set("p", strconv.Itoa(page))
+		values.Set(p.pageKey(), strconv.Itoa(page))
 	}
 	link.RawQuery = values.Encode()
 	return link.String()
 }
 
+func (p *Paginator) pageKey() string {
+	if p.key == "" {
+		return DefaultPageParam
+	}
+	return p.key
+}
+
 // PageLinkPrev Returns URL to the previous page.
 func (p *Paginator) PageLinkPrev() (link string) {
 	if p.HasPrev() {
@@ -164,3 +275,39 @@ func NewPaginator(url string, per int, nums interface{}) *Paginator {
 	p.SetNums(nums)
 	return &p
 }
+
+// Paginate instantiates a paginator for the current request from the page
+// and per-page query parameters, see PaginationConfig. It sets the
+// X-Total-Count header and an RFC 5988 Link header with the first, prev, next
+// and last pages, and makes Resource add the same links to collections.
+func (c *Context) Paginate(total interface{}) *Paginator {
+	cfg := c.paginationConfig()
+
+	per := cfg.PerPage
+	if n, err := strconv.Atoi(string(c.URI().QueryArgs().Peek(cfg.PerPageParam))); err == nil && n > 0 {
+		per = n
+	}
+	if per > cfg.MaxPerPage {
+		per = cfg.MaxPerPage
+	}
+
+	p := NewPaginator(string(c.RequestURI()), per, total)
+	p.key = cfg.PageParam
+
+	c.Response.Header.Set(HeaderXTotalCount, strconv.FormatInt(p.Nums(), 10))
+	if header := linkHeader(c.pageLinks(p)); header != "" {
+		c.Response.Header.Set(HeaderLink, header)
+	}
+
+	c.SetPaginator(p)
+	return p
+}
+
+// linkHeader formats links as an RFC 5988 Link header value.
+func linkHeader(links []*Link) string {
+	parts := make([]string, 0, len(links))
+	for _, link := range links {
+		parts = append(parts, fmt.Sprintf("<%s>; rel=\"%s\"", link.Href, link.Rel))
+	}
+	return strings.Join(parts, ", ")
+}
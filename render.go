@@ -0,0 +1,205 @@
+package valse
+
+import (
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/vmihailenco/msgpack"
+)
+
+// Encoder encodes response values for Context.Render.
+type Encoder interface {
+	// ContentType returns the value sent in the Content-Type header.
+	ContentType() string
+	// Encode marshals v.
+	Encode(v interface{}) ([]byte, error)
+}
+
+type encoder struct {
+	contentType string
+	encode      func(v interface{}) ([]byte, error)
+}
+
+func (e *encoder) ContentType() string {
+	return e.contentType
+}
+
+func (e *encoder) Encode(v interface{}) ([]byte, error) {
+	return e.encode(v)
+}
+
+// NewEncoder returns an Encoder which sends contentType and marshals values
+// with fn.
+func NewEncoder(contentType string, fn func(v interface{}) ([]byte, error)) Encoder {
+	return &encoder{contentType, fn}
+}
+
+var (
+	// JSONEncoder encodes values as JSON.
+	JSONEncoder = NewEncoder(MIMEApplicationJSONCharsetUTF8, func(v interface{}) ([]byte, error) {
+		return json.Marshal(v)
+	})
+	// XMLEncoder encodes values as XML.
+	XMLEncoder = NewEncoder(MIMEApplicationXMLCharsetUTF8, xml.Marshal)
+	// MsgpackEncoder encodes values as MessagePack.
+	MsgpackEncoder = NewEncoder(MIMEApplicationMsgpack, msgpack.Marshal)
+	// ProtobufEncoder encodes values implementing proto.Message.
+	ProtobufEncoder = NewEncoder(MIMEApplicationProtobuf, func(v interface{}) ([]byte, error) {
+		m, ok := v.(proto.Message)
+		if !ok {
+			return nil, errors.New("protobuf: value does not implement proto.Message")
+		}
+		return proto.Marshal(m)
+	})
+)
+
+type mediaEncoder struct {
+	mediaType string
+	encoder   Encoder
+}
+
+// RegisterEncoder registers e for mediaType, replacing any encoder already
+// registered for it. Encoders are preferred in the order they were first
+// registered when the client accepts several equally.
+func (s *Server) RegisterEncoder(mediaType string, e Encoder) *Server {
+	mediaType = strings.ToLower(mediaType)
+	for i, me := range s.encoders {
+		if me.mediaType == mediaType {
+			s.encoders[i].encoder = e
+			return s
+		}
+	}
+	s.encoders = append(s.encoders, mediaEncoder{mediaType, e})
+	return s
+}
+
+func (s *Server) registerDefaultEncoders() {
+	s.RegisterEncoder(MIMEApplicationJSON, JSONEncoder)
+	s.RegisterEncoder(MIMEApplicationXML, XMLEncoder)
+	s.RegisterEncoder("text/xml", XMLEncoder)
+	s.RegisterEncoder(MIMEApplicationMsgpack, MsgpackEncoder)
+	s.RegisterEncoder("application/x-msgpack", MsgpackEncoder)
+	s.RegisterEncoder(MIMEApplicationProtobuf, ProtobufEncoder)
+	s.RegisterEncoder("application/x-protobuf", ProtobufEncoder)
+}
+
+// negotiate returns the registered encoder best matching the accept header.
+// The quality of an encoder is the one of the most specific range matching
+// its media type, so "application/json;q=0, */*" excludes JSON. At equal
+// quality, an encoder matched by a more specific range wins, then one
+// matched by a range listed earlier, then the first registered.
+func (s *Server) negotiate(accept string) Encoder {
+	if len(s.encoders) == 0 {
+		return nil
+	}
+	if strings.TrimSpace(accept) == "" {
+		return s.encoders[0].encoder
+	}
+
+	ranges := parseAccept(accept)
+	var best Encoder
+	var bestRange acceptRange
+	for _, me := range s.encoders {
+		rng, ok := matchAccept(ranges, me.mediaType)
+		if !ok || rng.q <= 0 {
+			continue
+		}
+		if best == nil || rng.q > bestRange.q ||
+			rng.q == bestRange.q && (rng.specificity > bestRange.specificity ||
+				rng.specificity == bestRange.specificity && rng.index < bestRange.index) {
+			best, bestRange = me.encoder, rng
+		}
+	}
+
+	return best
+}
+
+// Render encodes v with the encoder matching the Accept header and writes it
+// with the given status. It returns a 406 Entity when no registered encoder is
+// acceptable to the client.
+func (c *Context) Render(status int, v interface{}) error {
+	if c.s == nil || len(c.s.encoders) == 0 {
+		return ErrRendererNotRegistered
+	}
+
+	c.Response.Header.Add(HeaderVary, "Accept")
+
+	e := c.s.negotiate(string(c.Request.Header.Peek("Accept")))
+	if e == nil {
+		return NewHTTPMessage(StatusNotAcceptable)
+	}
+
+	bs, err := e.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	c.SetStatusCode(status)
+	c.Response.Header.Set(HeaderContentType, e.ContentType())
+	c.Response.SetBody(bs)
+	return nil
+}
+
+func matchMediaType(rng, mediaType string) bool {
+	if rng == "*/*" || rng == mediaType {
+		return true
+	}
+	if strings.HasSuffix(rng, "/*") {
+		return strings.HasPrefix(mediaType, rng[:len(rng)-1])
+	}
+	return false
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+	// specificity is 0 for */*, 1 for type/* and 2 for a full media type.
+	specificity int
+	// index is the position of the range in the header.
+	index int
+}
+
+// matchAccept returns the most specific of ranges matching mediaType.
+func matchAccept(ranges []acceptRange, mediaType string) (acceptRange, bool) {
+	var match acceptRange
+	found := false
+	for _, rng := range ranges {
+		if matchMediaType(rng.mediaType, mediaType) && (!found || rng.specificity > match.specificity) {
+			match, found = rng, true
+		}
+	}
+	return match, found
+}
+
+// parseAccept returns the media ranges of an Accept header, including the
+// ones excluded with q=0.
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if f, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		specificity := 2
+		if mediaType == "*/*" {
+			specificity = 0
+		} else if strings.HasSuffix(mediaType, "/*") {
+			specificity = 1
+		}
+		ranges = append(ranges, acceptRange{mediaType, q, specificity, len(ranges)})
+	}
+	return ranges
+}
@@ -0,0 +1,110 @@
+package valse
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/valyala/fasthttp"
+	"github.com/vmihailenco/msgpack"
+)
+
+func TestNegotiate(t *testing.T) {
+	s := New()
+
+	tests := []struct {
+		accept string
+		want   Encoder
+	}{
+		{"", JSONEncoder},
+		{"application/xml", XMLEncoder},
+		{"application/xml, application/json", XMLEncoder},
+		{"application/json;q=0.5, application/xml", XMLEncoder},
+		{"*/*", JSONEncoder},
+		{"application/json;q=0, */*", XMLEncoder},
+		{"application/*;q=0, */*", XMLEncoder}, // registered for text/xml
+		{"*/*, application/msgpack", MsgpackEncoder},
+		{"application/*;q=0.5, application/xml;q=0.5", XMLEncoder},
+		{"text/html", nil},
+		{"text/*, application/json;q=0", XMLEncoder},
+	}
+
+	for _, tt := range tests {
+		if got := s.negotiate(tt.accept); got != tt.want {
+			t.Errorf("negotiate(%q) = %T %v, want %T %v", tt.accept, got, got, tt.want, tt.want)
+		}
+	}
+}
+
+type renderItem struct {
+	XMLName xml.Name `json:"-" xml:"item" msgpack:"-"`
+	ID      int      `json:"id" xml:"id" msgpack:"id"`
+}
+
+func TestRender(t *testing.T) {
+	item := renderItem{ID: 7}
+	message := &wrappers.StringValue{Value: "seven"}
+	mustJSON, _ := json.Marshal(item)
+	mustXML, _ := xml.Marshal(item)
+	mustMsgpack, _ := msgpack.Marshal(item)
+	mustProtobuf, _ := proto.Marshal(message)
+
+	tests := []struct {
+		name   string
+		accept string
+		value  interface{}
+		status int
+		// contentType and body are the expected response, body is not
+		// checked when nil.
+		contentType string
+		body        []byte
+	}{
+		{name: "default", value: item, status: StatusCreated, contentType: MIMEApplicationJSONCharsetUTF8, body: mustJSON},
+		{name: "json", accept: "application/json", value: item, status: StatusCreated, contentType: MIMEApplicationJSONCharsetUTF8, body: mustJSON},
+		{name: "xml", accept: "application/xml", value: item, status: StatusCreated, contentType: MIMEApplicationXMLCharsetUTF8, body: mustXML},
+		{name: "text xml", accept: "text/xml", value: item, status: StatusOK, contentType: MIMEApplicationXMLCharsetUTF8, body: mustXML},
+		{name: "msgpack", accept: "application/msgpack", value: item, status: StatusAccepted, contentType: MIMEApplicationMsgpack, body: mustMsgpack},
+		{name: "protobuf", accept: "application/x-protobuf", value: message, status: StatusOK, contentType: MIMEApplicationProtobuf, body: mustProtobuf},
+		{name: "not acceptable", accept: "text/html", value: item, status: StatusNotAcceptable, contentType: MIMEApplicationProblemJSON},
+		{name: "all excluded", accept: "*/*;q=0", value: item, status: StatusNotAcceptable, contentType: MIMEApplicationProblemJSON},
+		{name: "encoding failure", accept: "application/protobuf", value: item, status: StatusInternalServerError, contentType: MIMEApplicationProblemJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWithConfig(Config{Logger: nopLogger{}})
+			s.Get("/", func(c *Context) error {
+				return c.Render(tt.status, tt.value)
+			})
+
+			rc := &fasthttp.RequestCtx{}
+			rc.Request.SetRequestURI("/")
+			if tt.accept != "" {
+				rc.Request.Header.Set("Accept", tt.accept)
+			}
+			s.GetHandler()(rc)
+
+			if got := rc.Response.StatusCode(); got != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, got)
+			}
+			if got := string(rc.Response.Header.ContentType()); got != tt.contentType {
+				t.Errorf("expected Content-Type %s, got %s", tt.contentType, got)
+			}
+			if got := string(rc.Response.Header.Peek(HeaderVary)); got != "Accept" {
+				t.Errorf("expected Vary Accept, got %q", got)
+			}
+			if tt.body != nil && !bytes.Equal(rc.Response.Body(), tt.body) {
+				t.Errorf("expected body %q, got %q", tt.body, rc.Response.Body())
+			}
+		})
+	}
+
+	t.Run("no encoders", func(t *testing.T) {
+		c := &Context{RequestCtx: &fasthttp.RequestCtx{}}
+		if err := c.Render(StatusOK, item); err != ErrRendererNotRegistered {
+			t.Errorf("expected %v, got %v", ErrRendererNotRegistered, err)
+		}
+	})
+}
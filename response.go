@@ -4,8 +4,9 @@ import "github.com/valyala/fasthttp"
 
 //Entity 结构体
 type Entity struct {
-	Code int    `json:"code"`
-	Msg  string `json:"message"`
+	Code   int          `json:"code"`
+	Msg    string       `json:"message"`
+	Errors []FieldError `json:"errors,omitempty"`
 }
 
 // New new entity
@@ -22,7 +23,7 @@ func NewHTTPMessage(code int, msg ...string) error {
 		m = msg[0]
 	}
 
-	return &Entity{code, m}
+	return &Entity{Code: code, Msg: m}
 }
 
 // WithMsg set msg
@@ -45,10 +46,14 @@ func (r *Entity) WithCode(newCode int) *Entity {
 
 //MarshalJSON entity json
 func (r *Entity) MarshalJSON() ([]byte, error) {
-	return json.Marshal(map[string]interface{}{
+	m := map[string]interface{}{
 		"code":    r.Code,
 		"message": r.Msg,
-	})
+	}
+	if len(r.Errors) != 0 {
+		m["errors"] = r.Errors
+	}
+	return json.Marshal(m)
 }
 
 func (r *Entity) Error() string {
@@ -67,7 +72,7 @@ func (r *Entity) EntityCode() int {
 
 //BuildEntity 新建实体函数
 func BuildEntity(newCode int, newMsg string) *Entity {
-	return &Entity{newCode, newMsg}
+	return &Entity{Code: newCode, Msg: newMsg}
 }
 
 //ResponseEntity 返回实体
@@ -1,10 +1,14 @@
 package valse
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,6 +20,9 @@ import (
 )
 
 type URI fasthttp.URI
+
+// LinksFactory builds the href of page n of the collection served at url.
+// It is used by Context.Resource for the pagination links of collections.
 type LinksFactory func(c *Context, url URI, n int) string
 
 type RequestHandler func(*Context) error
@@ -24,17 +31,20 @@ type ValseHTTPHandler interface {
 	ServeHTTP(*Context) error
 }
 
+// Skipper reports whether a middleware should be skipped for a request.
+type Skipper func(*Context) bool
+
+// DefaultSkipper never skips.
+func DefaultSkipper(*Context) bool {
+	return false
+}
+
 func notFoundOrErr(ctx *Context, err error) error {
 	if ctx.Response.StatusCode() == StatusNotFound || err == nil {
 		return nil
 	}
-	status := http.StatusInternalServerError
-	if e, ok := err.(*Entity); ok {
-		ctx.Error(e.Message(), e.EntityCode())
-		return nil
-	}
 
-	ctx.Error(err.Error(), status)
+	ctx.s.errorHandler(ctx, err)
 
 	return nil
 }
@@ -167,14 +177,43 @@ type Config struct {
 	//     * cONTENT-lenGTH -> Content-Length
 	DisableHeaderNamesNormalizing bool
 
-	// Logger, which is used by RequestCtx.Logger().
+	// Logger, which is used by RequestCtx.Logger() and, wrapped by
+	// NewFieldLogger, as the parent of the request loggers returned by
+	// Context.Log().
 	//
 	// By default standard logger from log package is used.
 	Logger Logger
 
+	// Builds the pagination hrefs of collections rendered by
+	// Context.Resource.
+	//
+	// By default the page is set in the query string of the request URI.
 	LinksFactory LinksFactory
+
+	// Query parameters and limits used by Context.Paginate and
+	// Context.Cursor.
+	Pagination PaginationConfig
+
+	// Layout of the links embedded by Context.Resource.
+	//
+	// LinkStyleHAL is used by default.
+	LinkStyle LinkStyle
+
+	// ErrorHandler handles errors returned by request handlers.
+	//
+	// DefaultErrorHandler is used if not set.
+	ErrorHandler ErrorHandler
+
+	// Hides the text of internal errors from clients if set to true.
+	//
+	// Errors other than *Entity and *strong.HTTPError are then answered
+	// with a bare 500 problem document.
+	Production bool
 }
 
+// HookFunc is a function run at a point in the server lifecycle.
+type HookFunc func() error
+
 type Server struct {
 	noCopy
 	s       *fasthttp.Server
@@ -183,8 +222,27 @@ type Server struct {
 	m       []MiddlewareHandler
 	p       sync.Pool
 
-	links LinksFactory
-	v     *validator.Validate
+	mu         sync.Mutex
+	onStart    []HookFunc
+	onShutdown []HookFunc
+	// stopping is set by Shutdown. serving is closed once Listen is
+	// accepting connections and stopped once Listen has returned.
+	stopping bool
+	serving  chan struct{}
+	stopped  chan struct{}
+
+	links      LinksFactory
+	linkStyle  LinkStyle
+	pagination PaginationConfig
+	v          *validator.Validate
+	encoders   []mediaEncoder
+
+	errorHandler ErrorHandler
+	production   bool
+	logger       FieldLogger
+
+	routes []Route
+	names  map[string]int
 }
 
 func (s *Server) Use(handlers ...interface{}) *Server {
@@ -229,7 +287,18 @@ func (s *Server) Mount(path string, group *Group) *Server {
 			p = filepath.Join(path, route.Path)
 		}
 
-		s.Route(route.Method, p, cpy(group.m, route.Handler)...)
+		handler, err := s.compose(cpy(group.m, route.Handler))
+		if err != nil {
+			panic(err)
+		}
+
+		s.handle(Route{
+			Method:      route.Method,
+			Path:        p,
+			Name:        route.Name,
+			Handler:     handler,
+			Middlewares: len(group.m) + route.Middlewares,
+		})
 	}
 	return s
 }
@@ -269,11 +338,87 @@ func (s *Server) Route(method, path string, handlers ...interface{}) *Server {
 		panic(err)
 	}
 
-	s.r.Handle(method, path, s.handleRequest(handler))
+	s.handle(Route{
+		Method:      method,
+		Path:        path,
+		Handler:     handler,
+		Middlewares: len(handlers) - 1,
+	})
+
+	return s
+}
+
+func (s *Server) handle(route Route) {
+	s.r.Handle(route.Method, route.Path, s.handleRequest(route.Handler))
+	s.routes = append(s.routes, route)
+	if route.Name != "" {
+		s.setName(len(s.routes)-1, route.Name)
+	}
+}
+
+func (s *Server) setName(i int, name string) {
+	if j, ok := s.names[name]; ok && j != i {
+		panic(fmt.Sprintf("route name '%s' already used by %s %s", name, s.routes[j].Method, s.routes[j].Path))
+	}
+	if old := s.routes[i].Name; old != "" && old != name {
+		delete(s.names, old)
+	}
+	s.routes[i].Name = name
+	s.names[name] = i
+}
 
+// Name names the most recently registered route, so its URL can be built
+// with URL.
+//
+//	s.Get("/users/:id", showUser).Name("user.show")
+func (s *Server) Name(name string) *Server {
+	if len(s.routes) == 0 {
+		panic("no route to name")
+	}
+	s.setName(len(s.routes)-1, name)
 	return s
 }
 
+// Routes returns the registered routes in registration order.
+func (s *Server) Routes() []Route {
+	out := make([]Route, len(s.routes))
+	copy(out, s.routes)
+	return out
+}
+
+// URL builds the path of the route named name. The params replace the
+// route's named and catch-all parameters in order. URL returns an empty
+// string if no route has that name, or if the number of params differs from
+// the number of parameters of the route, which is logged as an error.
+func (s *Server) URL(name string, params ...interface{}) string {
+	return s.url(s.logger, name, params)
+}
+
+// Link returns a link to the route named name, with its href built by URL.
+// It returns nil if URL returns an empty string.
+func (s *Server) Link(name string, params ...interface{}) *Link {
+	href := s.url(s.logger, name, params)
+	if href == "" {
+		return nil
+	}
+	return &Link{Href: href, Method: s.routes[s.names[name]].Method}
+}
+
+// url builds the path of the route named name and logs to log why it
+// cannot.
+func (s *Server) url(log FieldLogger, name string, params []interface{}) string {
+	i, ok := s.names[name]
+	if !ok {
+		return ""
+	}
+	u, err := buildURL(s.routes[i].Path, params)
+	if err != nil {
+		log.WithField("route", name).WithError(err).Error("cannot build URL")
+		return ""
+	}
+	return u
+}
+
 func (s *Server) toMiddlewareHandler(handler interface{}) (MiddlewareHandler, error) {
 	switch h := handler.(type) {
 	case func(*Context) error:
@@ -308,17 +453,149 @@ func (s *Server) GetHandler() fasthttp.RequestHandler {
 	return s.s.Handler
 }
 
+// OnStart registers hooks which are run, in order, before the server starts
+// listening. If a hook returns an error, Listen returns it without serving.
+func (s *Server) OnStart(hooks ...HookFunc) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onStart = append(s.onStart, hooks...)
+	return s
+}
+
+// OnShutdown registers hooks which are run, in order, by Shutdown after
+// in-flight requests have been drained.
+func (s *Server) OnShutdown(hooks ...HookFunc) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onShutdown = append(s.onShutdown, hooks...)
+	return s
+}
+
 //Listen 监听服务
 func (s *Server) Listen(address string) error {
-
+	s.mu.Lock()
 	if s.running {
+		s.mu.Unlock()
 		return errors.New("Already running")
 	}
 	s.running = true
+	s.stopping = false
+	s.serving = nil
+	s.stopped = make(chan struct{})
+	stopped := s.stopped
+	hooks := s.onStart
+	s.mu.Unlock()
+
+	defer func() {
+		s.setRunning(false)
+		close(stopped)
+	}()
+
+	for _, hook := range hooks {
+		if err := hook(); err != nil {
+			return err
+		}
+	}
 
 	s.serverHandler()
 
-	return s.s.ListenAndServe(address)
+	ln, err := net.Listen("tcp4", address)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		return ln.Close()
+	}
+	l := &notifyListener{Listener: ln, accepting: make(chan struct{})}
+	s.serving = l.accepting
+	s.mu.Unlock()
+
+	err = s.s.Serve(l)
+	l.notify()
+	return err
+}
+
+// Shutdown gracefully shuts down the server. It stops accepting new
+// connections, waits for in-flight requests to finish and for Listen to
+// return, and then runs the OnShutdown hooks. If ctx expires first,
+// ctx.Err() is returned and the hooks are not run, as requests may still be
+// using what they release. Shutdown can then be called again if the server
+// is still running.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.running || s.stopping {
+		s.mu.Unlock()
+		return errors.New("Not running")
+	}
+	s.stopping = true
+	serving, stopped := s.serving, s.stopped
+	hooks := s.onShutdown
+	s.mu.Unlock()
+
+	if err := s.stop(ctx, serving, stopped); err != nil {
+		s.mu.Lock()
+		s.stopping = false
+		s.mu.Unlock()
+		return err
+	}
+
+	var err error
+	for _, hook := range hooks {
+		if herr := hook(); herr != nil && err == nil {
+			err = herr
+		}
+	}
+	return err
+}
+
+// stop shuts the fasthttp server down once Listen serves, if it got that
+// far, and waits for Listen to return.
+func (s *Server) stop(ctx context.Context, serving, stopped chan struct{}) error {
+	if serving != nil {
+		// The listener is registered with the fasthttp server once it
+		// accepts connections.
+		select {
+		case <-serving:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := s.s.ShutdownWithContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) setRunning(running bool) {
+	s.mu.Lock()
+	s.running = running
+	s.mu.Unlock()
+}
+
+// notifyListener closes accepting when Accept is first called, which the
+// fasthttp server does after registering the listener for Shutdown.
+type notifyListener struct {
+	net.Listener
+	once      sync.Once
+	accepting chan struct{}
+}
+
+func (l *notifyListener) Accept() (net.Conn, error) {
+	l.notify()
+	return l.Listener.Accept()
+}
+
+func (l *notifyListener) notify() {
+	l.once.Do(func() { close(l.accepting) })
 }
 
 func (s *Server) compose(handlers []interface{}) (RequestHandler, error) {
@@ -399,6 +676,16 @@ func NewWithConfig(config Config) *Server {
 }
 
 func (s *Server) init(config *Config) {
+	s.errorHandler = config.ErrorHandler
+	if s.errorHandler == nil {
+		s.errorHandler = DefaultErrorHandler
+	}
+	s.production = config.Production
+	s.logger = NewFieldLogger(config.Logger)
+	s.links = config.LinksFactory
+	s.linkStyle = config.LinkStyle
+	s.pagination = config.Pagination
+	s.pagination.setDefaults()
 
 	s.p = sync.Pool{
 		New: func() interface{} {
@@ -410,14 +697,38 @@ func (s *Server) init(config *Config) {
 
 }
 
+// Validator returns the validator used by Context.Bind and Context.Validate,
+// so custom validations can be registered on it.
+func (s *Server) Validator() *validator.Validate {
+	return s.v
+}
+
+// newValidator returns a validator which reports fields by their json name.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
 func newWithServer(server *fasthttp.Server, config *Config) *Server {
 
 	s := &Server{
-		s: server,
-		r: fasthttprouter.New(),
-		v: validator.New(),
+		s:     server,
+		r:     fasthttprouter.New(),
+		v:     newValidator(),
+		names: make(map[string]int),
 	}
 
+	s.registerDefaultEncoders()
 	s.init(config)
 
 	return s
@@ -1,9 +1,11 @@
-package valse
+package valse_test
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/xwinie/valse"
 )
@@ -34,8 +36,25 @@ func TestServer(t *testing.T) {
 		return ctx.JSON(string(bytes.TrimLeft(ctx.RequestURI(), "/")))
 	})
 
-	s.Listen(":3000")
+	started := make(chan struct{})
+	s.OnStart(func() error {
+		close(started)
+		return nil
+	})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.Listen("127.0.0.1:0")
+	}()
+	<-started
 
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
 }
 
 // func TestHttpServer(t *testing.T) {
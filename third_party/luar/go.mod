@@ -0,0 +1,5 @@
+module github.com/stevedonovan/luar
+
+go 1.24.0
+
+require github.com/aarzilli/golua v0.0.0-20250217091409-248753f411c4
@@ -0,0 +1,352 @@
+// Package luar converts values between Go and the C Lua states of
+// github.com/aarzilli/golua.
+//
+// No version of github.com/stevedonovan/luar can be fetched from the module
+// proxy, so valse replaces the module with this package, which implements
+// the part of its API valse uses: Init, Register, GoToLua, LuaToGo, Map and
+// Slice. Drop the replace directive once upstream can be pinned again.
+package luar
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/aarzilli/golua/lua"
+)
+
+// Map is a Go map converted to and from a Lua table.
+type Map map[string]interface{}
+
+// Slice is a Go slice converted to and from a Lua sequence.
+type Slice []interface{}
+
+var (
+	mapType   = reflect.TypeOf(Map(nil))
+	sliceType = reflect.TypeOf(Slice(nil))
+)
+
+// Init returns a new state with all the standard libraries open.
+func Init() *lua.State {
+	L := lua.NewState()
+	L.OpenLibs()
+	return L
+}
+
+// Register converts values with GoToLua and sets them as the fields of the
+// global table named table, which is created if needed. With an empty name
+// they are set as globals.
+func Register(L *lua.State, table string, values Map) {
+	if table == "" {
+		for name, v := range values {
+			GoToLua(L, v)
+			L.SetGlobal(name)
+		}
+		return
+	}
+
+	L.GetGlobal(table)
+	if !L.IsTable(-1) {
+		L.Pop(1)
+		L.NewTable()
+		L.PushValue(-1)
+		L.SetGlobal(table)
+	}
+	for name, v := range values {
+		GoToLua(L, v)
+		L.SetField(-2, name)
+	}
+	L.Pop(1)
+}
+
+// GoToLua pushes a onto the stack of L. Booleans, numbers and strings
+// become the Lua values, errors their message. Maps, slices, arrays and the
+// exported fields of structs are copied into tables. Functions become Lua
+// functions converting their arguments with LuaToGo and pushing their
+// results; a lua.LuaGoFunction is pushed as is. Pointers and interfaces
+// push the value they point to and nil pushes nil. Other values are pushed
+// as userdata LuaToGo converts back.
+func GoToLua(L *lua.State, a interface{}) {
+	pushValue(L, reflect.ValueOf(a))
+}
+
+func pushValue(L *lua.State, v reflect.Value) {
+	if !v.IsValid() {
+		L.PushNil()
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		if v.IsNil() {
+			L.PushNil()
+			return
+		}
+	}
+
+	switch a := v.Interface().(type) {
+	case lua.LuaGoFunction:
+		L.PushGoFunction(a)
+		return
+	case func(*lua.State) int:
+		L.PushGoFunction(a)
+		return
+	case error:
+		L.PushString(a.Error())
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		L.PushBoolean(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		L.PushInteger(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		L.PushNumber(float64(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		L.PushNumber(v.Float())
+	case reflect.String:
+		L.PushString(v.String())
+	case reflect.Ptr, reflect.Interface:
+		pushValue(L, v.Elem())
+	case reflect.Map:
+		L.CreateTable(0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			pushValue(L, iter.Key())
+			pushValue(L, iter.Value())
+			L.SetTable(-3)
+		}
+	case reflect.Slice, reflect.Array:
+		L.CreateTable(v.Len(), 0)
+		for i := 0; i < v.Len(); i++ {
+			pushValue(L, v.Index(i))
+			L.RawSeti(-2, i+1)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		L.CreateTable(0, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.PkgPath == "" {
+				pushValue(L, v.Field(i))
+				L.SetField(-2, f.Name)
+			}
+		}
+	case reflect.Func:
+		pushFunc(L, v)
+	default:
+		L.PushGoStruct(v.Interface())
+	}
+}
+
+// pushFunc pushes a Lua function calling fn. Missing arguments are passed
+// as zero values; a conversion error is raised as a Lua error.
+func pushFunc(L *lua.State, fn reflect.Value) {
+	t := fn.Type()
+	L.PushGoFunction(func(L *lua.State) int {
+		args, err := funcArgs(L, t)
+		if err != nil {
+			L.RaiseError(err.Error())
+		}
+		var results []reflect.Value
+		if t.IsVariadic() {
+			results = fn.CallSlice(args)
+		} else {
+			results = fn.Call(args)
+		}
+		for _, r := range results {
+			pushValue(L, r)
+		}
+		return len(results)
+	})
+}
+
+func funcArgs(L *lua.State, t reflect.Type) ([]reflect.Value, error) {
+	top := L.GetTop()
+	fixed := t.NumIn()
+	if t.IsVariadic() {
+		fixed--
+	}
+
+	args := make([]reflect.Value, 0, t.NumIn())
+	for i := 0; i < fixed; i++ {
+		v, err := toValue(L, i+1, t.In(i))
+		if err != nil {
+			return nil, fmt.Errorf("bad argument #%d: %v", i+1, err)
+		}
+		args = append(args, v)
+	}
+	if !t.IsVariadic() {
+		return args, nil
+	}
+
+	rest := reflect.MakeSlice(t.In(fixed), 0, 0)
+	for i := fixed + 1; i <= top; i++ {
+		v, err := toValue(L, i, t.In(fixed).Elem())
+		if err != nil {
+			return nil, fmt.Errorf("bad argument #%d: %v", i, err)
+		}
+		rest = reflect.Append(rest, v)
+	}
+	return append(args, rest), nil
+}
+
+// LuaToGo converts the value at idx to the type a points to and stores it
+// in *a. nil converts to the zero value, tables to maps, slices and
+// structs, and Lua values to an interface{} become bool, float64, string,
+// Slice for non-empty sequences and Map for other tables.
+func LuaToGo(L *lua.State, idx int, a interface{}) error {
+	rv := reflect.ValueOf(a)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("luar: LuaToGo needs a non-nil pointer")
+	}
+	v, err := toValue(L, idx, rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+	rv.Elem().Set(v)
+	return nil
+}
+
+func toValue(L *lua.State, idx int, t reflect.Type) (reflect.Value, error) {
+	if idx < 0 {
+		idx = L.GetTop() + idx + 1
+	}
+	lt := L.Type(idx)
+	if lt == lua.LUA_TNIL || lt == lua.LUA_TNONE {
+		return reflect.Zero(t), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		if v, ok := natural(L, idx, lt); ok && v.Type().AssignableTo(t) {
+			out := reflect.New(t).Elem()
+			out.Set(v)
+			return out, nil
+		}
+	case reflect.Bool:
+		if lt == lua.LUA_TBOOLEAN {
+			return reflect.ValueOf(L.ToBoolean(idx)).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		if L.IsNumber(idx) {
+			return reflect.ValueOf(L.ToNumber(idx)).Convert(t), nil
+		}
+	case reflect.String:
+		if lt == lua.LUA_TSTRING || lt == lua.LUA_TNUMBER {
+			// ToString converts a number in place, which would confuse
+			// Next when idx is a key, so convert a copy.
+			L.PushValue(idx)
+			s := L.ToString(-1)
+			L.Pop(1)
+			return reflect.ValueOf(s).Convert(t), nil
+		}
+	case reflect.Slice:
+		if lt == lua.LUA_TTABLE {
+			return toSlice(L, idx, t)
+		}
+	case reflect.Map:
+		if lt == lua.LUA_TTABLE {
+			return toMap(L, idx, t)
+		}
+	case reflect.Struct:
+		if lt == lua.LUA_TTABLE {
+			return toStruct(L, idx, t)
+		}
+	case reflect.Ptr:
+		if lt == lua.LUA_TTABLE {
+			e, err := toValue(L, idx, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			p := reflect.New(t.Elem())
+			p.Elem().Set(e)
+			return p, nil
+		}
+	}
+
+	if L.IsGoStruct(idx) {
+		if v := reflect.ValueOf(L.ToGoStruct(idx)); v.IsValid() && v.Type().AssignableTo(t) {
+			return v, nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("luar: cannot convert Lua %s to Go %s", L.Typename(int(lt)), t)
+}
+
+// natural returns the value at idx converted to the Go type closest to its
+// Lua type.
+func natural(L *lua.State, idx int, lt lua.LuaValType) (reflect.Value, bool) {
+	switch lt {
+	case lua.LUA_TBOOLEAN:
+		return reflect.ValueOf(L.ToBoolean(idx)), true
+	case lua.LUA_TNUMBER:
+		return reflect.ValueOf(L.ToNumber(idx)), true
+	case lua.LUA_TSTRING:
+		return reflect.ValueOf(L.ToString(idx)), true
+	case lua.LUA_TTABLE:
+		t := mapType
+		if L.ObjLen(idx) > 0 {
+			t = sliceType
+		}
+		v, err := toValue(L, idx, t)
+		return v, err == nil
+	}
+	if L.IsGoStruct(idx) {
+		v := reflect.ValueOf(L.ToGoStruct(idx))
+		return v, v.IsValid()
+	}
+	return reflect.Value{}, false
+}
+
+func toSlice(L *lua.State, idx int, t reflect.Type) (reflect.Value, error) {
+	n := int(L.ObjLen(idx))
+	s := reflect.MakeSlice(t, n, n)
+	for i := 0; i < n; i++ {
+		L.RawGeti(idx, i+1)
+		e, err := toValue(L, -1, t.Elem())
+		L.Pop(1)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		s.Index(i).Set(e)
+	}
+	return s, nil
+}
+
+func toMap(L *lua.State, idx int, t reflect.Type) (reflect.Value, error) {
+	m := reflect.MakeMap(t)
+	L.PushNil()
+	for L.Next(idx) != 0 {
+		k, err := toValue(L, -2, t.Key())
+		var e reflect.Value
+		if err == nil {
+			e, err = toValue(L, -1, t.Elem())
+		}
+		if err != nil {
+			L.Pop(2)
+			return reflect.Value{}, err
+		}
+		m.SetMapIndex(k, e)
+		L.Pop(1)
+	}
+	return m, nil
+}
+
+func toStruct(L *lua.State, idx int, t reflect.Type) (reflect.Value, error) {
+	s := reflect.New(t).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		L.GetField(idx, f.Name)
+		e, err := toValue(L, -1, f.Type)
+		L.Pop(1)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		s.Field(i).Set(e)
+	}
+	return s, nil
+}
@@ -0,0 +1,3 @@
+module github.com/kildevaeld/strong
+
+go 1.24.0
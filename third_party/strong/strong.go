@@ -0,0 +1,83 @@
+// Package strong holds the HTTP constants and the HTTPError type valse
+// shares with github.com/kildevaeld/strong.
+//
+// No version of github.com/kildevaeld/strong can be fetched from the module
+// proxy, so valse replaces the module with this package, which declares the
+// names valse imports. Drop the replace directive once upstream can be
+// pinned again.
+package strong
+
+import "net/http"
+
+// HTTP methods
+const (
+	CONNECT = "CONNECT"
+	DELETE  = "DELETE"
+	GET     = "GET"
+	HEAD    = "HEAD"
+	OPTIONS = "OPTIONS"
+	PATCH   = "PATCH"
+	POST    = "POST"
+	PUT     = "PUT"
+	TRACE   = "TRACE"
+)
+
+// HTTP status codes
+const (
+	StatusOK = http.StatusOK
+)
+
+// MIME types
+const (
+	MIMEApplicationJSON            = "application/json"
+	MIMEApplicationJSONCharsetUTF8 = MIMEApplicationJSON + "; " + charsetUTF8
+	MIMETextPlain                  = "text/plain"
+	MIMETextPlainCharsetUTF8       = MIMETextPlain + "; " + charsetUTF8
+)
+
+const (
+	charsetUTF8 = "charset=utf-8"
+)
+
+// Headers
+const (
+	HeaderAuthorization = "Authorization"
+	HeaderContentType   = "Content-Type"
+	HeaderOrigin        = "Origin"
+	HeaderVary          = "Vary"
+
+	HeaderAccessControlRequestMethod    = "Access-Control-Request-Method"
+	HeaderAccessControlRequestHeaders   = "Access-Control-Request-Headers"
+	HeaderAccessControlAllowOrigin      = "Access-Control-Allow-Origin"
+	HeaderAccessControlAllowMethods     = "Access-Control-Allow-Methods"
+	HeaderAccessControlAllowHeaders     = "Access-Control-Allow-Headers"
+	HeaderAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
+	HeaderAccessControlExposeHeaders    = "Access-Control-Expose-Headers"
+	HeaderAccessControlMaxAge           = "Access-Control-Max-Age"
+)
+
+// HTTPError is an error answered with an HTTP status code.
+type HTTPError struct {
+	Code    int
+	Message string
+}
+
+// NewHTTPError returns an HTTPError for code. The message defaults to the
+// status text of code.
+func NewHTTPError(code int, msg ...string) *HTTPError {
+	he := &HTTPError{Code: code, Message: http.StatusText(code)}
+	if len(msg) > 0 {
+		he.Message = msg[0]
+	}
+	return he
+}
+
+// Error returns the message of the error.
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+// Errors
+var (
+	ErrUnauthorized = NewHTTPError(http.StatusUnauthorized)
+)
@@ -101,10 +101,16 @@ func compose(handlers []interface{}) (RequestHandler, error) {
 	return routeHandler, nil
 }
 
+// Route describes a registered route.
 type Route struct {
 	Method  string
 	Path    string
-	Handler RequestHandler
+	Name    string
+	Handler RequestHandler `json:"-"`
+
+	// Middlewares is the number of route specific middlewares, including
+	// those added by the groups the route was mounted from.
+	Middlewares int
 }
 
 // ToInt64 convert any numeric value to int64